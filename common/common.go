@@ -16,6 +16,9 @@ const (
 	Url3     = "https://stopelectionrigging.com/"
 	Url4     = "https://bkokfi.com/"
 	FileName = "update.txt"
+
+	// UserAgent 请求时伪装成浏览器
+	UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"
 )
 
 var PngDir = map[string]string{
@@ -30,8 +33,35 @@ var PngView = map[string]Viewport{
 	Url3: Viewport{Width: 1280, Height: 7100},
 }
 
+// PngGif 开启后视觉告警额外发送新旧变化区域来回切换的 gif
+var PngGif = map[string]bool{
+	Url3: true,
+}
+
+// 视觉差异图的输出模式
+const (
+	DiffModeRect    = "rect"    // 默认：在当前截图上画红框
+	DiffModeHeatmap = "heatmap" // 在当前截图上叠加变化强度热力图
+)
+
+// PngDiffMode 各目标差异图的输出模式，未配置时使用 DiffModeRect
+var PngDiffMode = map[string]string{
+	Url3: DiffModeRect,
+}
+
+// PngAlertPercent 变化面积低于该百分比时不发送差异图和对比图，也不更新基线；文本更新通知不受影响
+var PngAlertPercent = map[string]float64{
+	Url3: 0.05,
+}
+
 var HashFile = filepath.Join(getProjectRoot(), "hash_store.json")
 
+// StateDir 各类检查（网络请求、接口字段等）保存上一次结果的目录
+var StateDir = filepath.Join(getProjectRoot(), "state")
+
+// AssetDir asset 模式归档文件每个版本的目录
+var AssetDir = filepath.Join(getProjectRoot(), "assets")
+
 func getProjectRoot() string {
 	dir, _ := os.Getwd() // 程序启动时的工作目录
 	return dir
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvInt 读取整数环境变量，未设置或格式错误时返回默认值
+func EnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("环境变量 %s=%q 不是整数，使用默认值 %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// EnvDuration 读取时长环境变量（如 "90s"、"2m"），未设置或格式错误时返回默认值
+func EnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("环境变量 %s=%q 不是有效时长，使用默认值 %s", key, v, def)
+		return def
+	}
+	return d
+}
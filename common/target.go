@@ -0,0 +1,302 @@
+package common
+
+import (
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+// 抓取方式
+const (
+	ModeStatic  = "static"  // net/http + goquery 抓取页面文本
+	ModeDynamic = "dynamic" // Playwright 渲染后抓取页面文本
+	ModeJSON    = "json"    // 请求 JSON 接口，按 JSONPaths 选取字段比较
+	ModeFeed    = "feed"    // RSS/Atom，按条目 ID 比较，每个新条目单独推送
+	ModeSitemap = "sitemap" // sitemap.xml 或 sitemap 索引，按 URL 和 lastmod 比较
+	ModeCrawl   = "crawl"   // 从 URL 开始抓取同源链接，页面增减时告警，并自动监控发现的页面
+	ModeLinks   = "links"   // 检查页面上所有链接的状态，链接增减、开始跳转或失效时告警
+	ModeAsset   = "asset"   // 跟踪 PDF、图片等文件的内容哈希，每个版本归档，变化时发送新文件
+)
+
+// Window 每天的一段时间，格式 "HH:MM"，To 早于 From 时表示跨过午夜
+type Window struct {
+	From string
+	To   string
+}
+
+// Adaptive 自适应轮询：检测到变化后间隔缩短到 Min，页面保持不变时每次乘以 Growth 逐步放宽到 Max；
+// 连续抓取失败时间隔按 2 的幂次退避，最长 MaxBackoff（不能小于 Max，未配置时为 4 倍 Max）
+type Adaptive struct {
+	Min        time.Duration
+	Max        time.Duration
+	Growth     float64
+	MaxBackoff time.Duration
+}
+
+// HTTPConfig 静态抓取的 HTTP 设置，字符串值支持 $VAR 形式引用环境变量（用于密码、令牌等）
+type HTTPConfig struct {
+	// Timeout 单次请求超时，默认 30 秒
+	Timeout time.Duration
+	// Proxy 代理地址，支持 http://、https://、socks5://
+	Proxy string
+	// Headers 额外请求头
+	Headers map[string]string
+	// Cookies 启用 cookie jar，在多次检查间保留服务器下发的 cookie
+	Cookies bool
+	// BasicUser/BasicPass Basic 认证；BearerToken Bearer 认证，二者择一
+	BasicUser   string
+	BasicPass   string
+	BearerToken string
+	// CAFile 额外信任的 CA 证书（PEM）；InsecureSkipVerify 跳过证书校验
+	CAFile             string
+	InsecureSkipVerify bool
+	// MaxRedirects 最多跟随的重定向次数，0 使用默认值 10，小于 0 不跟随
+	MaxRedirects int
+	// AcceptStatus 视为成功的状态码，默认只接受 200
+	AcceptStatus []int
+}
+
+// 页面操作类型
+const (
+	StepClick        = "click"         // 点击 Selector
+	StepFill         = "fill"          // 在 Selector 中填入 Value
+	StepWaitSelector = "wait_selector" // 等待 Selector 出现
+	StepWaitLoad     = "wait_load"     // 等待页面网络空闲，用于提交表单后的跳转
+	StepWaitTimeout  = "wait_timeout"  // 固定等待 Timeout
+	StepScroll       = "scroll"        // 滚动到 Selector；未指定时逐屏滚动到底部触发懒加载，再回到顶部
+	StepEvaluate     = "evaluate"      // 执行 Value 中的 JS 表达式或函数
+)
+
+// Step 一个 Playwright 页面操作，fill 的 Value 支持 $VAR 形式引用环境变量，Timeout 默认 10 秒。
+// Optional 的步骤失败时忽略，适合只偶尔出现的 cookie 横幅、弹窗
+type Step struct {
+	Action   string
+	Selector string
+	Value    string
+	Timeout  time.Duration
+	Optional bool
+}
+
+// 页面就绪条件类型
+const (
+	WaitLoad           = "load"            // Value 为 load / domcontentloaded / networkidle
+	WaitSelector       = "selector"        // 等待 Value 选择器出现
+	WaitSelectorHidden = "selector_hidden" // 等待 Value 选择器消失，如加载动画
+	WaitFunction       = "function"        // 等待 Value 中的 JS 谓词返回真值
+	WaitText           = "text"            // 等待页面出现 Value 文本
+	WaitDOMStable      = "dom_stable"      // DOM 连续 Stable 时长没有变化
+)
+
+// WaitRule 页面就绪条件，按顺序逐条等待，Timeout 默认 30 秒
+type WaitRule struct {
+	Kind    string
+	Value   string
+	Stable  time.Duration
+	Timeout time.Duration
+}
+
+// Intercept Playwright 请求拦截策略
+type Intercept struct {
+	// BlockTypes 屏蔽的资源类型，如 image、media、font、stylesheet
+	BlockTypes []string
+	// BlockTrackers 屏蔽内置列表中的统计、广告域名
+	BlockTrackers bool
+	// BlockDomains 额外屏蔽的域名，包含子域名
+	BlockDomains []string
+	// Stubs URL 通配符（* 匹配任意字符）到本地文件，匹配的请求直接返回文件内容
+	Stubs map[string]string
+	// Headers 注入到每个请求的请求头
+	Headers map[string]string
+}
+
+// Login 登录配置：会话保存在 StateFile，检测到过期（跳转到 ExpiredURL 或出现 ExpiredSelector）时
+// 打开 URL 执行 Steps 重新登录；两种检测都未配置时只在没有保存的会话时登录
+type Login struct {
+	// URL 登录页地址，为空时在目标页面上直接执行 Steps
+	URL   string
+	Steps []Step
+	// CookiesFile 首次运行时导入的 cookie，Playwright 格式的 cookie 数组或 StorageState 文件
+	CookiesFile string
+	// StateFile 会话 StorageState 的保存位置，默认 sessions/<域名>.json
+	StateFile       string
+	ExpiredURL      string
+	ExpiredSelector string
+}
+
+// StatePath 返回会话文件路径
+func (l *Login) StatePath(targetURL string) string {
+	if l.StateFile != "" {
+		return l.StateFile
+	}
+	host := "default"
+	if u, err := url.Parse(targetURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return filepath.Join("sessions", host+".json")
+}
+
+// Crawl crawl 模式的抓取范围。Include/Exclude 为正则，匹配链接的路径和查询串，
+// 配置了 Include 时只保留匹配的链接，Exclude 优先
+type Crawl struct {
+	// Depth 从种子页出发最多跟随几层链接，默认 2
+	Depth int
+	// MaxPages 最多记录的页面数（包含种子），默认 100
+	MaxPages int
+	Include  []string
+	Exclude  []string
+	// Mode 发现的页面的抓取方式，默认 static；其余调度、HTTP 等设置沿用种子目标
+	Mode string
+}
+
+// LinkCheck links 模式的设置
+type LinkCheck struct {
+	// Selector 提取链接的元素，默认 a[href]；没有 href 的元素取 src
+	Selector string
+	// Concurrency 同时检查的链接数，默认 5
+	Concurrency int
+	// Exclude 跳过匹配这些正则的链接，如分享按钮、统计跳转链接
+	Exclude []string
+}
+
+// Assets asset 模式的设置
+type Assets struct {
+	// Selector 从 URL 页面中发现文件链接，如 `a[href$=".pdf"], img.sign`；为空时 URL 本身就是文件
+	Selector string
+	// Keep 每个文件保留的归档版本数，默认 20
+	Keep int
+}
+
+// Infra 目标主机的 DNS 记录（A/AAAA/CNAME/NS/MX）和 TLS 证书检查，变化时告警，
+// 同一主机的多个目标共用检查结果
+type Infra struct {
+	// Interval 两次检查的最小间隔，默认 1 小时
+	Interval time.Duration
+	// ExpiryDays 证书剩余有效期少于该天数时告警，默认 14
+	ExpiryDays int
+	// Resolver DNS 服务器地址（host:port），默认使用系统解析
+	Resolver string
+}
+
+// 关键词规则类型
+const (
+	RuleContains    = "contains"     // 文本包含 Value 时命中
+	RuleNotContains = "not_contains" // 文本不包含 Value 时命中
+	RuleRegex       = "regex"        // 文本匹配正则 Value 时命中
+	RuleCountAbove  = "count_above"  // Value 出现次数超过 Count 时命中
+)
+
+// Rule 关键词规则，每次检查在提取的文本上计算，命中状态变化时告警
+type Rule struct {
+	// Name 告警中显示的名称，默认由类型和 Value 生成；同一目标内不能重复
+	Name       string
+	Kind       string
+	Value      string
+	Count      int
+	IgnoreCase bool
+}
+
+// Extractor 从页面提取一个数值，每次检查追加到该目标的时间序列，可通过状态接口的 /history 查询
+type Extractor struct {
+	// Name 序列名称，同一目标内唯一
+	Name string
+	// Selector 取第一个匹配元素的文本，为空时使用整页文本；只对 static、dynamic 模式生效
+	Selector string
+	// Regex 从文本中取数值，有分组时取第一个分组，默认取第一个数字；千分位逗号会被去掉
+	Regex string
+	// Above/Below 数值高于/低于阈值时告警，回到范围内时再通知一次
+	Above *float64
+	Below *float64
+	// ChangePercent 与上一次的值相比变化超过该百分比时告警，如 10 表示 10%
+	ChangePercent float64
+}
+
+// Target 一个监控目标及其调度配置
+type Target struct {
+	// URL 同一 URL 可以配置多个不同抓取方式的目标，但 static 和 dynamic 只能二选一
+	URL  string
+	Mode string
+	// Visual 内容变化时额外做截图视觉对比
+	Visual bool
+
+	// Interval 两次检查之间的间隔，配置了 Cron 或 Adaptive 时不生效
+	Interval time.Duration
+	// Adaptive 自适应轮询，不能和 Cron 同时使用，例如：
+	//	&Adaptive{Min: 20 * time.Second, Max: 10 * time.Minute, Growth: 1.5, MaxBackoff: 30 * time.Minute}
+	Adaptive *Adaptive
+	// Jitter 每次调度额外增加 [0, Jitter) 的随机延迟，避免所有目标同时请求
+	Jitter time.Duration
+	// Cron 标准 5 段 cron 表达式（分 时 日 月 周，本地时区），配置多条时取最早的下一次时间，
+	// 例如白天每分钟、夜间每 15 分钟：{"* 9-21 * * *", "*/15 22-23,0-8 * * *"}
+	Cron []string
+	// Blackout 在这些时间段内不检查，顺延到时间段结束
+	Blackout []Window
+
+	// HTTP 静态抓取的 HTTP 设置
+	HTTP HTTPConfig
+	// Login 需要登录的页面，只对 Playwright 抓取和截图生效
+	Login *Login
+	// Wait 页面就绪条件，未配置时等待网络空闲（30 秒）；配置后打开页面只等到 DOMContentLoaded，
+	// 其余由规则决定，适合存在长轮询、网络永远不会空闲的页面
+	Wait []WaitRule
+	// Response 记录 static 抓取的响应状态、跳转链和响应头（Server、Cache-Control、CSP 等安全头、Set-Cookie 名），
+	// 状态码变化、页面开始跳转、跳转链变化、安全相关响应头变化或出现新的 cookie 名时告警
+	Response bool
+	// Meta 比较 static/dynamic 抓取的页面元数据（title、meta、Open Graph、canonical、JSON-LD），
+	// 字段变化时单独告警，不影响内容哈希
+	Meta bool
+	// Network 记录 Playwright 抓取时的网络请求，新增第三方域名、脚本内容变化或请求开始失败时告警
+	Network bool
+	// Intercept 请求拦截策略，对 Playwright 抓取和截图都生效
+	Intercept *Intercept
+	// JSONPaths json 模式下监控的字段，如 "data.price"、"items.#.id"、"$.items[0].stock"，
+	// 为空时监控整个响应；只比较选中的字段，键顺序变化不算更新
+	JSONPaths []string
+	// Crawl crawl 模式的抓取范围，例如只收录商店分类页：
+	//	&Crawl{Depth: 2, Include: []string{"^/the-patriot-shop/", "^/categories/"}, Exclude: []string{`\?`}}
+	Crawl *Crawl
+	// Links links 模式的设置，为空时使用默认值
+	Links *LinkCheck
+	// Assets asset 模式的设置
+	Assets *Assets
+	// Rules 关键词规则，例如：
+	//	{Kind: RuleContains, Value: "SOLD OUT", IgnoreCase: true},
+	//	{Kind: RuleRegex, Value: `\$\d+(\.\d{2})?`, Name: "价格"},
+	Rules []Rule
+	// Extract 数值提取，例如价格变化超过 10% 时告警：
+	//	{Name: "hat", Selector: ".product-price", Regex: `\$([\d,.]+)`, ChangePercent: 10},
+	Extract []Extractor
+	// Infra 主机的 DNS 和证书检查，和内容检查一起运行
+	Infra *Infra
+	// Root Playwright 抓取时提取文本的根元素，默认 #app
+	Root string
+	// Steps 打开页面后、提取文本或截图前执行的操作，例如：
+	//	{Action: StepClick, Selector: "#cookie-accept", Optional: true},
+	//	{Action: StepClick, Selector: "text=View All"},
+	//	{Action: StepScroll},
+	Steps []Step
+}
+
+// Targets 所有监控目标。需要登录的页面示例（密码从环境变量读取）：
+//
+//	{URL: "https://store.gavinnewsom.com/account.php", Mode: ModeDynamic, Interval: 10 * time.Minute,
+//		Login: &Login{
+//			URL: "https://store.gavinnewsom.com/login.php",
+//			Steps: []Step{
+//				{Action: StepFill, Selector: "#login_email", Value: "$STORE_USER"},
+//				{Action: StepFill, Selector: "#login_pass", Value: "$STORE_PASS"},
+//				{Action: StepClick, Selector: "input[type=submit]"},
+//				{Action: StepWaitLoad},
+//			},
+//			ExpiredURL: "login.php",
+//		}},
+var Targets = []Target{
+	{URL: Url1, Mode: ModeStatic, Interval: 20 * time.Second, Jitter: 5 * time.Second},
+	{URL: Url2, Mode: ModeStatic, Interval: 20 * time.Second, Jitter: 5 * time.Second},
+	{URL: Url3, Mode: ModeStatic, Visual: true, Interval: 20 * time.Second, Jitter: 5 * time.Second},
+	{URL: Url4, Mode: ModeDynamic, Interval: 20 * time.Second, Jitter: 5 * time.Second, Root: "#app", Intercept: &Intercept{
+		BlockTypes: []string{"image", "media", "font"},
+	}, Wait: []WaitRule{
+		{Kind: WaitLoad, Value: "networkidle", Timeout: 30 * time.Second},
+		{Kind: WaitSelector, Value: "#app", Timeout: 10 * time.Second},
+	}},
+}
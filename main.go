@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"github.com/joho/godotenv"
 	"github.com/playwright-community/playwright-go"
 	"log"
+	"store/common"
 	"store/service"
+	"store/utils"
+	"time"
 )
 
 func init() {
@@ -31,14 +35,21 @@ func main() {
 	}
 	defer pw.Stop()
 
-	// 启动 Chromium 浏览器（headless）
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-	})
-	if err != nil {
-		log.Fatalf("could not launch browser: %v", err)
+	// 启动 Chromium 浏览器（headless），所有页面任务共用，崩溃或断开后自动重启
+	launch := func() (playwright.Browser, error) {
+		return pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+			Headless: playwright.Bool(true),
+		})
 	}
-	defer browser.Close()
-	service.Hash(browser)
-	service.SetupGracefulShutdown()
+	manager := utils.NewBrowserManager(launch, 2*time.Second, 2*time.Minute)
+	manager.Start()
+	pool := utils.NewBrowserPool(manager,
+		common.EnvInt("BROWSER_MAX_PAGES", 1),
+		common.EnvDuration("BROWSER_JOB_TIMEOUT", 90*time.Second))
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.Hash(ctx, pool)
+	service.SetupGracefulShutdown(cancel)
 }
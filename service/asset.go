@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"store/common"
+	"strings"
+	"time"
+)
+
+// Telegram Bot API 上传文件的大小上限
+const maxTelegramDocument = 50 << 20
+
+// 每个文件默认保留的归档版本数
+const defaultAssetKeep = 20
+
+// AssetVersion 一个文件最近一次的版本
+type AssetVersion struct {
+	Hash     string    `json:"hash"`
+	Size     int       `json:"size"`
+	TextHash string    `json:"text_hash,omitempty"` // PDF 提取文本的哈希，无法提取时为空
+	Archive  string    `json:"archive"`             // 归档文件路径
+	Fetched  time.Time `json:"fetched"`
+}
+
+// document 需要随更新通知发送的文件
+type document struct {
+	path    string
+	caption string
+}
+
+// assetURLs 返回需要跟踪的文件地址：未配置 Selector 时目标本身就是文件，否则从页面中发现
+func assetURLs(ctx context.Context, t common.Target) ([]string, error) {
+	if t.Assets == nil || t.Assets.Selector == "" {
+		return []string{t.URL}, nil
+	}
+	body, err := fetchBody(ctx, t, t.URL, "text/html")
+	if err != nil {
+		return nil, err
+	}
+	links, err := extractLinks(t.URL, body, t.Assets.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("%s 解析 HTML 失败:%w", t.URL, err)
+	}
+	return links, nil
+}
+
+// archiveAsset 把文件的一个版本保存到 assets/<文件地址>/<时间>_<短哈希><扩展名>，
+// 只保留最近 keep 个版本
+func archiveAsset(assetURL string, data []byte, hash string, at time.Time, keep int) (string, error) {
+	ext := ""
+	if u, err := url.Parse(assetURL); err == nil {
+		ext = strings.ToLower(path.Ext(u.Path))
+	}
+	if ext == "" {
+		ext = ".bin"
+		if isPDF(data) {
+			ext = ".pdf"
+		}
+	}
+	dir := filepath.Join(common.AssetDir, safeName(assetURL))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	p := filepath.Join(dir, at.Format("20060102-150405")+"_"+hash[:8]+ext)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return "", err
+	}
+	return p, pruneArchive(dir, keep)
+}
+
+// pruneArchive 删除目录中较旧的版本，文件名以时间开头，按名称排序即按时间排序
+func pruneArchive(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// pdfText 用 pdftotext（poppler-utils）提取 PDF 文本，未安装或提取失败时返回 false
+func pdfText(ctx context.Context, file string) (string, bool) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", false
+	}
+	out, err := exec.CommandContext(ctx, bin, "-enc", "UTF-8", file, "-").Output()
+	if err != nil {
+		log.Printf("%s 提取 PDF 文本失败: %v", file, err)
+		return "", false
+	}
+	return strings.Join(strings.Fields(string(out)), " "), true
+}
+
+// assetHash 下载并哈希所有文件，新版本归档后随更新通知发送
+func assetHash(ctx context.Context, t common.Target) (checkResult, error) {
+	urls, err := assetURLs(ctx, t)
+	if err != nil {
+		return checkResult{}, err
+	}
+	var old map[string]AssetVersion
+	ok, err := loadState("asset", t.URL, &old)
+	if err != nil {
+		return checkResult{}, fmt.Errorf("%s 读取文件记录失败: %w", t.URL, err)
+	}
+
+	keep := defaultAssetKeep
+	if t.Assets != nil && t.Assets.Keep > 0 {
+		keep = t.Assets.Keep
+	}
+	now := time.Now()
+	cur := make(map[string]AssetVersion, len(urls))
+	var res checkResult
+	var texts []string
+	for _, u := range urls {
+		data, err := fetchBody(ctx, t, u, "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return checkResult{}, ctx.Err()
+			}
+			// 单个文件下载失败时沿用上次记录，不当作删除
+			log.Printf("%s 下载失败: %v", t.URL, err)
+			if prev, found := old[u]; found {
+				cur[u] = prev
+			}
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		prev, found := old[u]
+		if found && prev.Hash == hash {
+			cur[u] = prev
+			continue
+		}
+
+		v := AssetVersion{Hash: hash, Size: len(data), Fetched: now}
+		if v.Archive, err = archiveAsset(u, data, hash, now, keep); err != nil {
+			return checkResult{}, fmt.Errorf("%s 归档失败: %w", u, err)
+		}
+		textNote := ""
+		if isPDF(data) {
+			if text, extracted := pdfText(ctx, v.Archive); extracted {
+				textSum := sha256.Sum256([]byte(text))
+				v.TextHash = hex.EncodeToString(textSum[:])
+				texts = append(texts, u+"\n"+text)
+				switch {
+				case found && prev.TextHash == v.TextHash:
+					textNote = "，文本未变化"
+				case found && prev.TextHash != "":
+					textNote = "，文本已变化"
+				}
+			}
+		}
+		cur[u] = v
+		log.Printf("%s 文件已归档: %s", u, v.Archive)
+
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("新增文件: %s (%d 字节)", u, v.Size)
+		if found {
+			line = fmt.Sprintf("文件变化: %s (%d → %d 字节%s)", u, prev.Size, v.Size, textNote)
+		}
+		res.changes = append(res.changes, line)
+		if v.Size <= maxTelegramDocument {
+			res.documents = append(res.documents, document{path: v.Archive, caption: line})
+		} else {
+			log.Printf("%s 超过 Telegram 文件大小限制，不发送", u)
+		}
+	}
+	if ok {
+		var removed []string
+		for u := range old {
+			if _, found := cur[u]; !found {
+				removed = append(removed, "文件删除: "+u)
+			}
+		}
+		sort.Strings(removed)
+		res.changes = append(res.changes, removed...)
+	}
+	if err := saveState("asset", t.URL, cur); err != nil {
+		return checkResult{}, fmt.Errorf("%s 保存文件记录失败: %w", t.URL, err)
+	}
+
+	keys := make([]string, 0, len(cur))
+	for u := range cur {
+		keys = append(keys, u)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, u := range keys {
+		fmt.Fprintf(&sb, "%s\t%s\n", u, cur[u].Hash)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	res.hash = hex.EncodeToString(sum[:])
+	// update.txt 记录文件列表，能提取文本的 PDF 附上文本
+	res.text = sb.String()
+	if len(texts) > 0 {
+		res.text += "\n" + strings.Join(texts, "\n\n")
+	}
+	return res, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"store/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssetHash(t *testing.T) {
+	useTempStateDir(t)
+	useTempAssetDir(t)
+	files := map[string]string{
+		"/signs.pdf": "%PDF-1.4 v1",
+		"/yard.png":  "png v1",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = w.Write([]byte(`<a href="/signs.pdf">signs</a><a href="/yard.png">yard</a><a href="/about">about</a>`))
+			return
+		}
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL + "/", Mode: common.ModeAsset,
+		Assets: &common.Assets{Selector: `a[href$=".pdf"], a[href$=".png"]`}}
+	first, err := assetHash(ctx, target)
+	if err != nil || len(first.changes) != 0 || len(first.documents) != 0 {
+		t.Fatalf("首次检查只归档: %+v err=%v", first, err)
+	}
+
+	same, err := assetHash(ctx, target)
+	if err != nil || same.hash != first.hash {
+		t.Fatalf("文件未变化时哈希应相同: err=%v", err)
+	}
+
+	files["/signs.pdf"] = "%PDF-1.4 v2!"
+	res, err := assetHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.hash == first.hash {
+		t.Fatal("文件变化后哈希应改变")
+	}
+	want := []string{"文件变化: " + srv.URL + "/signs.pdf (11 → 12 字节)"}
+	if !reflect.DeepEqual(res.changes, want) {
+		t.Errorf("changes = %q, want %q", res.changes, want)
+	}
+	if len(res.documents) != 1 {
+		t.Fatalf("documents = %+v", res.documents)
+	}
+	data, err := os.ReadFile(res.documents[0].path)
+	if err != nil || string(data) != files["/signs.pdf"] {
+		t.Errorf("归档内容 %q err=%v", data, err)
+	}
+}
+
+func TestArchiveAssetKeep(t *testing.T) {
+	useTempAssetDir(t)
+	at := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	var last string
+	for i := 0; i < 5; i++ {
+		data := []byte{byte(i)}
+		sum := sha256.Sum256(data)
+		p, err := archiveAsset("https://example.com/signs.pdf", data, hex.EncodeToString(sum[:]), at.Add(time.Duration(i)*time.Minute), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = p
+	}
+	entries, err := os.ReadDir(filepath.Dir(last))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("期望保留 3 个版本, 实际 %d", len(entries))
+	}
+	if _, err := os.Stat(last); err != nil {
+		t.Error("最新版本不应被删除")
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if data, err := readLimited(strings.NewReader("12345"), 5); err != nil || string(data) != "12345" {
+		t.Errorf("未超出上限时 = %q, %v", data, err)
+	}
+	if _, err := readLimited(strings.NewReader("123456"), 5); !errors.Is(err, errBodyTooLarge) {
+		t.Errorf("超出上限时应返回 errBodyTooLarge, 实际 %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"store/common"
+	"store/utils"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultCrawlDepth    = 2
+	defaultCrawlMaxPages = 100
+)
+
+// 不抓取的文件类型，这些链接只记录不展开
+var skipCrawlExt = map[string]bool{
+	".pdf": true, ".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".svg": true,
+	".zip": true, ".mp4": true, ".mp3": true, ".css": true, ".js": true, ".xml": true, ".ico": true,
+}
+
+// watched 一个自动监控的页面，done 在监控协程退出时关闭
+type watched struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	crawlMu sync.Mutex
+	// 种子 URL -> 自动监控的页面 -> 监控协程
+	crawled = map[string]map[string]watched{}
+)
+
+// compilePatterns 编译一组正则
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则 %q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// crawlPatterns 编译 Include/Exclude 正则
+func crawlPatterns(c *common.Crawl) (include, exclude []*regexp.Regexp, err error) {
+	if include, err = compilePatterns(c.Include); err != nil {
+		return nil, nil, err
+	}
+	if exclude, err = compilePatterns(c.Exclude); err != nil {
+		return nil, nil, err
+	}
+	return include, exclude, nil
+}
+
+// normalizeLink 把页面中的链接解析成绝对地址，去掉锚点，只保留与种子同源的 http(s) 链接
+func normalizeLink(base *url.URL, href string, seed *url.URL) (*url.URL, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return nil, false
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return nil, false
+	}
+	u.Fragment = ""
+	if u.Scheme != seed.Scheme || u.Host != seed.Host {
+		return nil, false
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, true
+}
+
+// crawl 从种子页开始广度优先抓取同源链接，返回排序后的页面列表（包含种子）
+func crawl(ctx context.Context, t common.Target) ([]string, error) {
+	c := t.Crawl
+	depth, maxPages := c.Depth, c.MaxPages
+	if depth <= 0 {
+		depth = defaultCrawlDepth
+	}
+	if maxPages <= 0 {
+		maxPages = defaultCrawlMaxPages
+	}
+	include, exclude, err := crawlPatterns(c)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := url.Parse(t.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%s 无效的种子地址: %w", t.URL, err)
+	}
+	allowed := func(u *url.URL) bool {
+		p := u.RequestURI()
+		for _, re := range exclude {
+			if re.MatchString(p) {
+				return false
+			}
+		}
+		if len(include) == 0 {
+			return true
+		}
+		for _, re := range include {
+			if re.MatchString(p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	type item struct {
+		u     *url.URL
+		depth int
+	}
+	seen := map[string]bool{seed.String(): true}
+	queue := []item{{seed, 0}}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur.depth >= depth || skipCrawlExt[strings.ToLower(path.Ext(cur.u.Path))] {
+			continue
+		}
+		body, err := fetchBody(ctx, t, cur.u.String(), "text/html")
+		if err != nil {
+			if cur.depth == 0 {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("%s 抓取失败，跳过: %v", t.URL, err)
+			continue
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		doc.Find("a[href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			href, _ := s.Attr("href")
+			u, ok := normalizeLink(cur.u, href, seed)
+			if !ok || seen[u.String()] || !allowed(u) {
+				return true
+			}
+			if len(seen) >= maxPages {
+				return false
+			}
+			seen[u.String()] = true
+			queue = append(queue, item{u, cur.depth + 1})
+			return true
+		})
+	}
+
+	pages := make([]string, 0, len(seen))
+	for p := range seen {
+		pages = append(pages, p)
+	}
+	sort.Strings(pages)
+	return pages, nil
+}
+
+// crawlHash 抓取种子下的所有页面，页面列表的变化作为更新内容
+func crawlHash(ctx context.Context, t common.Target) (checkResult, error) {
+	pages, err := crawl(ctx, t)
+	if err != nil {
+		return checkResult{}, err
+	}
+	text := strings.Join(pages, "\n")
+	sum := sha256.Sum256([]byte(text))
+	res := checkResult{text: text, hash: hex.EncodeToString(sum[:]), pages: pages}
+
+	var old []string
+	ok, err := loadState("crawl", t.URL, &old)
+	if err != nil {
+		return res, fmt.Errorf("%s 读取页面记录失败: %w", t.URL, err)
+	}
+	if err := saveState("crawl", t.URL, pages); err != nil {
+		return res, fmt.Errorf("%s 保存页面记录失败: %w", t.URL, err)
+	}
+	if ok {
+		res.changes = diffPages(old, pages)
+	}
+	return res, nil
+}
+
+// diffPages 比较两次抓取的页面列表
+func diffPages(old, cur []string) []string {
+	known := map[string]bool{}
+	for _, p := range old {
+		known[p] = true
+	}
+	var out []string
+	for _, p := range cur {
+		if !known[p] {
+			out = append(out, "新增页面: "+p)
+		}
+		delete(known, p)
+	}
+	var gone []string
+	for p := range known {
+		gone = append(gone, "页面消失: "+p)
+	}
+	sort.Strings(gone)
+	return append(out, gone...)
+}
+
+// isConfigured 页面已经作为独立目标配置时不重复监控
+func isConfigured(child common.Target) bool {
+	key := targetKey(child)
+	for _, t := range common.Targets {
+		if targetKey(t) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// watchedByOther 页面是否已经由其他种子启动了监控，调用方需持有 crawlMu
+func watchedByOther(seed, page string) bool {
+	for s, running := range crawled {
+		if _, ok := running[page]; ok && s != seed {
+			return true
+		}
+	}
+	return false
+}
+
+// watchPages 为抓取发现的 HTML 页面启动监控协程，使用种子的调度和抓取设置；
+// 不再出现的页面停止监控，等协程退出后清除 hash 和状态
+func watchPages(ctx context.Context, bot *utils.TelegramBot, pool *utils.BrowserPool, seed common.Target, pages []string) {
+	crawlMu.Lock()
+	defer crawlMu.Unlock()
+	running := crawled[seed.URL]
+	if running == nil {
+		running = map[string]watched{}
+		crawled[seed.URL] = running
+	}
+
+	current := map[string]bool{}
+	for _, p := range pages {
+		if !isHTMLPage(p) {
+			continue
+		}
+		current[p] = true
+		if _, ok := running[p]; ok {
+			continue
+		}
+		child := pageTarget(seed, p)
+		if isConfigured(child) || watchedByOther(seed.URL, p) {
+			continue
+		}
+		// 视觉对比依赖按 URL 配置的截图目录，自动发现的页面不做截图
+		child.Visual = false
+		sched, err := newSchedule(child)
+		if err != nil {
+			log.Printf("%s 调度配置错误: %v", p, err)
+			continue
+		}
+		pageCtx, cancel := context.WithCancel(ctx)
+		w := watched{cancel: cancel, done: make(chan struct{})}
+		running[p] = w
+		monitors.Add(1)
+		go func() {
+			defer monitors.Done()
+			defer close(w.done)
+			monitor(pageCtx, bot, pool, child, sched)
+		}()
+		log.Printf("%s 开始监控发现的页面: %s", seed.URL, p)
+	}
+
+	var stopped []string
+	for p, w := range running {
+		if !current[p] {
+			w.cancel()
+			stopped = append(stopped, p)
+		}
+	}
+	// 监控协程退出前可能还会写入 Store，必须等它结束再清除
+	for _, p := range stopped {
+		<-running[p].done
+		delete(running, p)
+		child := pageTarget(seed, p)
+		mu.Lock()
+		delete(Store, targetKey(child))
+		mu.Unlock()
+		deleteStatus(targetKey(child))
+		dropClient(child)
+		log.Printf("%s 停止监控已消失的页面: %s", seed.URL, p)
+	}
+}
+
+// pageTarget 自动发现的页面对应的监控目标，沿用种子的设置，抓取方式默认 static
+func pageTarget(seed common.Target, page string) common.Target {
+	child := seed
+	child.URL, child.Mode, child.Crawl = page, seed.Crawl.Mode, nil
+	if child.Mode == "" {
+		child.Mode = common.ModeStatic
+	}
+	return child
+}
+
+// isHTMLPage 排除 PDF、图片等不适合作为页面监控的链接
+func isHTMLPage(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && !skipCrawlExt[strings.ToLower(path.Ext(u.Path))]
+}
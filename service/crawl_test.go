@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"store/common"
+	"testing"
+	"time"
+)
+
+func TestCrawl(t *testing.T) {
+	pages := map[string]string{
+		"/":       `<a href="/shop/a">a</a><a href="shop/b#top">b</a><a href="/shop/a?sort=1">sorted</a><a href="https://secure.actblue.com/">x</a><a href="/about">about</a>`,
+		"/shop/a": `<a href="/shop/a/deep">deep</a><a href="/shop/sign.pdf">pdf</a>`,
+		"/shop/b": `<a href="/">home</a>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>" + body + "</body></html>"))
+	}))
+	defer srv.Close()
+
+	target := common.Target{URL: srv.URL + "/", Mode: common.ModeCrawl, Crawl: &common.Crawl{
+		Depth:   2,
+		Include: []string{"^/shop/"},
+		Exclude: []string{`\?`},
+	}}
+	got, err := crawl(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		srv.URL + "/",
+		srv.URL + "/shop/a",
+		srv.URL + "/shop/a/deep",
+		srv.URL + "/shop/b",
+		srv.URL + "/shop/sign.pdf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %q\nwant %q", got, want)
+	}
+
+	target.Crawl.MaxPages = 2
+	if got, _ = crawl(context.Background(), target); len(got) != 2 {
+		t.Errorf("MaxPages 限制无效: %q", got)
+	}
+}
+
+func TestDiffPages(t *testing.T) {
+	got := diffPages([]string{"/a", "/b", "/c"}, []string{"/a", "/d"})
+	want := []string{"新增页面: /d", "页面消失: /b", "页面消失: /c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWatchPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>" + r.URL.Path + "</body></html>"))
+	}))
+	defer srv.Close()
+
+	useTempStateDir(t)
+	oldStore := Store
+	Store = HashStore{}
+	t.Cleanup(func() { Store = oldStore })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seed := common.Target{URL: srv.URL + "/", Mode: common.ModeCrawl, Interval: time.Hour, Crawl: &common.Crawl{}}
+	page := srv.URL + "/a"
+	watchPages(ctx, nil, nil, seed, []string{page, srv.URL + "/report.pdf"})
+
+	crawlMu.Lock()
+	n := len(crawled[seed.URL])
+	crawlMu.Unlock()
+	if n != 1 {
+		t.Fatalf("期望只监控 1 个 HTML 页面, 实际 %d", n)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		_, ok := Store[page]
+		mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("页面未完成首次检查")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 页面消失后监控协程退出，hash 随之清除
+	watchPages(ctx, nil, nil, seed, nil)
+	mu.Lock()
+	_, ok := Store[page]
+	mu.Unlock()
+	if ok || len(crawled[seed.URL]) != 0 {
+		t.Error("停止监控后应清除页面的 hash")
+	}
+}
@@ -1,63 +1,83 @@
 package service
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/playwright-community/playwright-go"
+	"store/common"
+	"store/utils"
 	"strings"
 )
 
-func dynamicHash(browser playwright.Browser, url string) (string, string, error) {
-	context, _ := browser.NewContext(playwright.BrowserNewContextOptions{
-		UserAgent: playwright.String("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36"),
-	})
-	defer context.Close()
-
-	// 新建页面
-	page, err := context.NewPage()
-	if err != nil {
-		return "", "", fmt.Errorf("%s error creating new page: %w", url, err)
+// dynamicHash 渲染页面后提取文本并计算哈希，开启 Network 时同时返回本次的网络请求记录
+func dynamicHash(ctx context.Context, pool *utils.BrowserPool, t common.Target) (checkResult, *utils.NetworkSnapshot, error) {
+	url := t.URL
+	root := t.Root
+	if root == "" {
+		root = "#app"
 	}
-	defer page.Close()
+	var (
+		html     string
+		pageHTML string
+		snap     *utils.NetworkSnapshot
+	)
+	job := func(page playwright.Page) error {
+		var rec *utils.NetworkRecorder
+		if t.Network {
+			rec = utils.RecordNetwork(page)
+		}
 
-	// 过滤图片和字体资源
-	err = page.Route("**/*", func(route playwright.Route) {
-		req := route.Request()
-		rt := req.ResourceType()
-		if rt == "image" || rt == "media" || rt == "font" {
-			_ = route.Abort()
-			return
+		// 按配置屏蔽资源、替换请求
+		err := utils.ApplyIntercept(page, t.Intercept)
+		if err != nil {
+			return err
 		}
-		_ = route.Continue()
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("could not set route: %w", err)
-	}
-	// 打开页面并等待网络空闲
-	_, err = page.Goto(url, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(30000),
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("could not goto: %w", err)
-	}
-	// 等待 #app 渲染
-	if _, err = page.WaitForSelector("#app", playwright.PageWaitForSelectorOptions{
-		Timeout: playwright.Float(10000),
-	}); err != nil {
-		return "", "", fmt.Errorf("could not wait for selector: %w", err)
+		// 打开页面，需要登录时自动处理会话
+		if err = utils.Navigate(page, url, t.Login, utils.GotoOptions(t.Wait)); err != nil {
+			return err
+		}
+		// 等待页面就绪
+		if err = utils.WaitReady(page, t.Wait); err != nil {
+			return err
+		}
+		// 执行预置的页面操作
+		if err = utils.RunSteps(page, t.Steps); err != nil {
+			return err
+		}
+
+		html, err = page.InnerHTML(root)
+		if err != nil {
+			return fmt.Errorf("could not get html: %w", err)
+		}
+		// 元数据在 <head> 中，需要整页 HTML
+		if t.Meta {
+			if pageHTML, err = page.Content(); err != nil {
+				return fmt.Errorf("could not get page content: %w", err)
+			}
+		}
+		if rec != nil {
+			snap = rec.Snapshot()
+		}
+		return nil
 	}
 
-	html, err := page.InnerHTML("#app")
+	var err error
+	if t.Login != nil {
+		err = pool.DoSession(ctx, t.Login.StatePath(url), job)
+	} else {
+		err = pool.Do(ctx, job)
+	}
 	if err != nil {
-		return "", "", fmt.Errorf("%s could not get html: %w", url, err)
+		return checkResult{}, nil, fmt.Errorf("%s %w", url, err)
 	}
+
 	// 用 goquery 解析 HTML，提取纯文本
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
-		return "", "", fmt.Errorf("%s could not parse html: %w", url, err)
+		return checkResult{}, nil, fmt.Errorf("%s could not parse html: %w", url, err)
 	}
 	text := doc.Text()
 
@@ -68,5 +88,5 @@ func dynamicHash(browser playwright.Browser, url string) (string, string, error)
 	// 对纯文本做哈希
 	sha256Hash := sha256.Sum256([]byte(normalized))
 	fmt.Println("Text SHA256:", hex.EncodeToString(sha256Hash[:]))
-	return normalized, hex.EncodeToString(sha256Hash[:]), nil
+	return checkResult{text: normalized, hash: hex.EncodeToString(sha256Hash[:]), html: html, page: pageHTML}, snap, nil
 }
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"sort"
+	"store/common"
+	"strings"
+)
+
+const (
+	// sitemap 索引最多展开的子 sitemap 数
+	maxChildSitemaps = 50
+	// 新增条目逐条推送的上限，超过后合并成一条消息
+	maxItemAlerts = 10
+)
+
+// FeedItem RSS/Atom 条目或 sitemap 中的一个 URL
+type FeedItem struct {
+	ID      string `json:"id"`
+	Title   string `json:"title,omitempty"`
+	Link    string `json:"link,omitempty"`
+	Updated string `json:"updated,omitempty"` // pubDate / updated / lastmod
+}
+
+// feedDoc 同时匹配 RSS 2.0、RSS 1.0 (RDF)、Atom、sitemap 和 sitemap 索引，只按本地元素名解析
+type feedDoc struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items    []rssItem    `xml:"item"`
+	Entries  []atomEntry  `xml:"entry"`
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type rssItem struct {
+	GUID    string `xml:"guid"`
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"` // RSS 1.0 的 dc:date
+}
+
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published"`
+}
+
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// parseFeed 解析 feed 或 sitemap，返回条目和 sitemap 索引中的子 sitemap 地址
+func parseFeed(data []byte) ([]FeedItem, []string, error) {
+	// .xml.gz 形式的 sitemap 需要先解压
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if data, err = readLimited(zr, maxBodySize); err != nil {
+			return nil, nil, err
+		}
+	}
+	var doc feedDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	var items []FeedItem
+	for _, it := range append(doc.Channel.Items, doc.Items...) {
+		item := FeedItem{ID: firstNonEmpty(it.GUID, it.Link, it.Title), Title: it.Title, Link: it.Link,
+			Updated: firstNonEmpty(it.PubDate, it.Date)}
+		items = append(items, item)
+	}
+	for _, e := range doc.Entries {
+		link := ""
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		items = append(items, FeedItem{ID: firstNonEmpty(e.ID, link, e.Title), Title: e.Title, Link: link,
+			Updated: firstNonEmpty(e.Updated, e.Published)})
+	}
+	for _, u := range doc.URLs {
+		items = append(items, FeedItem{ID: u.Loc, Link: u.Loc, Updated: u.LastMod})
+	}
+	var children []string
+	for _, s := range doc.Sitemaps {
+		if s.Loc != "" {
+			children = append(children, strings.TrimSpace(s.Loc))
+		}
+	}
+	for i := range items {
+		items[i].ID = strings.TrimSpace(items[i].ID)
+		items[i].Title = strings.Join(strings.Fields(items[i].Title), " ")
+		items[i].Link = strings.TrimSpace(items[i].Link)
+		items[i].Updated = strings.TrimSpace(items[i].Updated)
+	}
+	return items, children, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// fetchFeedItems 抓取 feed 或 sitemap，sitemap 索引会展开一层子 sitemap
+func fetchFeedItems(ctx context.Context, t common.Target) (map[string]FeedItem, error) {
+	accept := "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8"
+	body, err := fetchBody(ctx, t, t.URL, accept)
+	if err != nil {
+		return nil, err
+	}
+	items, children, err := parseFeed(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s 解析 XML 失败:%w", t.URL, err)
+	}
+	if len(children) > maxChildSitemaps {
+		log.Printf("%s 子 sitemap 共 %d 个，只检查前 %d 个", t.URL, len(children), maxChildSitemaps)
+		children = children[:maxChildSitemaps]
+	}
+	for _, child := range children {
+		body, err := fetchBody(ctx, t, child, accept)
+		if err != nil {
+			return nil, err
+		}
+		sub, _, err := parseFeed(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s 解析 XML 失败:%w", child, err)
+		}
+		items = append(items, sub...)
+	}
+
+	out := make(map[string]FeedItem, len(items))
+	for _, it := range items {
+		if it.ID != "" {
+			out[it.ID] = it
+		}
+	}
+	return out, nil
+}
+
+// diffFeed 比较两次抓取的条目，返回按 ID 排序的新增条目，以及删除、更新说明
+func diffFeed(old, cur map[string]FeedItem) ([]FeedItem, []string) {
+	var added []FeedItem
+	var changes []string
+	for id, it := range cur {
+		prev, ok := old[id]
+		switch {
+		case !ok:
+			added = append(added, it)
+		case prev.Updated != it.Updated:
+			changes = append(changes, fmt.Sprintf("更新: %s (%s → %s)", it.label(), prev.Updated, it.Updated))
+		}
+	}
+	for id, it := range old {
+		if _, ok := cur[id]; !ok {
+			changes = append(changes, "删除: "+it.label())
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].ID < added[j].ID })
+	sort.Strings(changes)
+	return added, changes
+}
+
+func (it FeedItem) label() string {
+	switch {
+	case it.Title != "" && it.Link != "":
+		return it.Title + " " + it.Link
+	case it.Link != "":
+		return it.Link
+	}
+	return firstNonEmpty(it.Title, it.ID)
+}
+
+// feedHash 检查 RSS/Atom feed 或 sitemap：哈希覆盖所有条目的 ID 和更新时间，
+// 每个新增条目单独推送一条告警，删除和更新附在更新通知中
+func feedHash(ctx context.Context, t common.Target) (checkResult, error) {
+	url := t.URL
+	cur, err := fetchFeedItems(ctx, t)
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	ids := make([]string, 0, len(cur))
+	for id := range cur {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var sb strings.Builder
+	for _, id := range ids {
+		it := cur[id]
+		fmt.Fprintf(&sb, "%s\t%s\t%s\n", it.ID, it.Updated, it.Title)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	res := checkResult{text: sb.String(), hash: hex.EncodeToString(sum[:])}
+
+	var old map[string]FeedItem
+	ok, err := loadState(t.Mode, url, &old)
+	if err != nil {
+		return res, fmt.Errorf("%s 读取条目记录失败: %w", url, err)
+	}
+	if err := saveState(t.Mode, url, cur); err != nil {
+		return res, fmt.Errorf("%s 保存条目记录失败: %w", url, err)
+	}
+	if !ok {
+		return res, nil
+	}
+
+	added, changes := diffFeed(old, cur)
+	for i, it := range added {
+		if i == maxItemAlerts {
+			var rest []string
+			for _, it := range added[i:] {
+				rest = append(rest, it.label())
+			}
+			res.alerts = append(res.alerts, formatChanges(fmt.Sprintf("%s 另有 %d 条新增", url, len(rest)), rest))
+			break
+		}
+		lines := []string{it.label()}
+		if it.Updated != "" {
+			lines = append(lines, "时间: "+it.Updated)
+		}
+		res.alerts = append(res.alerts, formatChanges(url+" 新增条目", lines))
+	}
+	if len(added) > 0 {
+		changes = append([]string{fmt.Sprintf("新增 %d 条", len(added))}, changes...)
+	}
+	res.changes = changes
+	return res, nil
+}
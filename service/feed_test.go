@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"store/common"
+	"strings"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel><title>x</title>
+<item><title>Sign up</title><link>https://bkokfi.com/a</link><guid>a-1</guid><pubDate>Mon, 01 Sep 2025 10:00:00 GMT</pubDate></item>
+<item><title>No guid</title><link>https://bkokfi.com/b</link></item>
+</channel></rss>`
+	items, _, err := parseFeed([]byte(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "a-1" || items[1].ID != "https://bkokfi.com/b" {
+		t.Fatalf("RSS: %+v", items)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>tag:x,1</id><title>Post</title>
+<link rel="self" href="https://x/self"/><link href="https://x/post"/><updated>2025-09-01T10:00:00Z</updated></entry></feed>`
+	items, _, err = parseFeed([]byte(atom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FeedItem{{ID: "tag:x,1", Title: "Post", Link: "https://x/post", Updated: "2025-09-01T10:00:00Z"}}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("Atom: %+v", items)
+	}
+}
+
+func TestFeedHashSitemapIndex(t *testing.T) {
+	useTempStateDir(t)
+	child := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<url><loc>https://store.gavinnewsom.com/a</loc><lastmod>2025-09-01</lastmod></url>
+<url><loc>https://store.gavinnewsom.com/b</loc><lastmod>2025-09-01</lastmod></url></urlset>`
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/products.xml" {
+			_, _ = w.Write([]byte(child))
+			return
+		}
+		_, _ = w.Write([]byte(`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>` + srv.URL + `/products.xml</loc></sitemap></sitemapindex>`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL + "/sitemap.xml", Mode: common.ModeSitemap}
+	first, err := feedHash(ctx, target)
+	if err != nil || len(first.alerts) != 0 || len(first.changes) != 0 {
+		t.Fatalf("首次检查只记录: %+v err=%v", first, err)
+	}
+
+	child = strings.Replace(child, "/b</loc><lastmod>2025-09-01", "/c</loc><lastmod>2025-09-02", 1)
+	child = strings.Replace(child, "/a</loc><lastmod>2025-09-01", "/a</loc><lastmod>2025-09-03", 1)
+	res, err := feedHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.hash == first.hash {
+		t.Fatal("条目变化后哈希应改变")
+	}
+	if len(res.alerts) != 1 || !strings.Contains(res.alerts[0], "https://store.gavinnewsom.com/c") {
+		t.Errorf("alerts = %q", res.alerts)
+	}
+	want := []string{
+		"新增 1 条",
+		"删除: https://store.gavinnewsom.com/b",
+		"更新: https://store.gavinnewsom.com/a (2025-09-01 → 2025-09-03)",
+	}
+	if !reflect.DeepEqual(res.changes, want) {
+		t.Errorf("changes = %q, want %q", res.changes, want)
+	}
+}
+
+func TestFeedHashCrossHostSitemap(t *testing.T) {
+	useTempStateDir(t)
+	var childAuth, indexAuth string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		childAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`<urlset><url><loc>https://cdn.example/a</loc></url></urlset>`))
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		indexAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`<sitemapindex><sitemap><loc>` + other.URL + `/sitemap.xml</loc></sitemap></sitemapindex>`))
+	}))
+	defer srv.Close()
+
+	target := common.Target{URL: srv.URL + "/sitemap.xml", Mode: common.ModeSitemap,
+		HTTP: common.HTTPConfig{BearerToken: "secret"}}
+	if _, err := feedHash(context.Background(), target); err != nil {
+		t.Fatal(err)
+	}
+	if indexAuth != "Bearer secret" {
+		t.Errorf("目标自己的域名应带认证信息, 实际 %q", indexAuth)
+	}
+	if childAuth != "" {
+		t.Errorf("其他域名的子 sitemap 不应带认证信息, 实际 %q", childAuth)
+	}
+}
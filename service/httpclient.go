@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"store/common"
+	"sync"
+	"time"
+)
+
+const defaultHTTPTimeout = 30 * time.Second
+
+// 单个响应体读取的上限，防止异常的大文件占满内存
+const maxBodySize = 64 << 20
+
+var (
+	clientMu sync.Mutex
+	// 代理和 TLS 设置相同的目标共用一个 Transport，复用连接池
+	transports = map[string]*http.Transport{}
+	// 每个目标（按 targetKey）一个 Client，保存各自的超时、重定向策略和 cookie jar
+	clients = map[string]*http.Client{}
+)
+
+// httpClient 返回目标对应的 http.Client，首次使用时按配置创建
+func httpClient(t common.Target) (*http.Client, error) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	key := targetKey(t)
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+	cfg := t.HTTP
+	tr, err := transportLocked(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s HTTP 配置错误: %w", t.URL, err)
+	}
+
+	c := &http.Client{Transport: tr, Timeout: cfg.Timeout}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultHTTPTimeout
+	}
+	if cfg.Cookies {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		c.Jar = jar
+	}
+	switch {
+	case cfg.MaxRedirects < 0:
+		c.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	case cfg.MaxRedirects > 0:
+		limit := cfg.MaxRedirects
+		c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > limit {
+				return fmt.Errorf("重定向次数超过 %d", limit)
+			}
+			return nil
+		}
+	}
+	clients[key] = c
+	return c, nil
+}
+
+// linkClient 返回检查链接用的 Client：与目标共用 Transport、超时和 cookie jar，
+// 但始终按默认策略跟随重定向，目标配置 MaxRedirects 时链接状态也不会停在 3xx
+func linkClient(t common.Target) (*http.Client, error) {
+	c, err := httpClient(t)
+	if err != nil {
+		return nil, err
+	}
+	lc := *c
+	lc.CheckRedirect = nil
+	return &lc, nil
+}
+
+// dropClient 删除目标缓存的 Client，停止监控的页面调用，Transport 仍由其他目标共用
+func dropClient(t common.Target) {
+	clientMu.Lock()
+	delete(clients, targetKey(t))
+	clientMu.Unlock()
+}
+
+func transportLocked(cfg common.HTTPConfig) (*http.Transport, error) {
+	key := fmt.Sprintf("%s|%s|%t", cfg.Proxy, cfg.CAFile, cfg.InsecureSkipVerify)
+	if tr, ok := transports[key]; ok {
+		return tr, nil
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConnsPerHost = 4
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(os.ExpandEnv(cfg.Proxy))
+		if err != nil {
+			return nil, fmt.Errorf("无效代理地址: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("不支持的代理协议: %s", proxyURL.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+	if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+		pool, err := certPool(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify, RootCAs: pool}
+	}
+	transports[key] = tr
+	return tr, nil
+}
+
+// certPool 返回系统根证书加上 caFile 中的证书，caFile 为空时返回 nil（只用系统根证书）
+func certPool(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("CA 证书中没有有效的 PEM 证书")
+	}
+	return pool, nil
+}
+
+// applyHTTPConfig 设置目标配置的请求头和认证信息
+func applyHTTPConfig(req *http.Request, cfg common.HTTPConfig) {
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, os.ExpandEnv(v))
+	}
+	switch {
+	case cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+os.ExpandEnv(cfg.BearerToken))
+	case cfg.BasicUser != "":
+		req.SetBasicAuth(os.ExpandEnv(cfg.BasicUser), os.ExpandEnv(cfg.BasicPass))
+	}
+}
+
+// statusAccepted 判断响应状态码是否视为成功
+func statusAccepted(cfg common.HTTPConfig, code int) bool {
+	if len(cfg.AcceptStatus) == 0 {
+		return code == http.StatusOK
+	}
+	return slices.Contains(cfg.AcceptStatus, code)
+}
+
+// fetchBody 使用目标的 HTTP 配置请求 url（可以不是目标本身，如子 sitemap），返回响应体；
+// 请求头和认证信息只发给目标自己的域名
+func fetchBody(ctx context.Context, t common.Target, url, accept string) ([]byte, error) {
+	client, err := httpClient(t)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s 请求创建失败:%w", url, err)
+	}
+	req.Header.Set("User-Agent", common.UserAgent)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sameHost(t.URL, req.URL) {
+		applyHTTPConfig(req, t.HTTP)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s 请求发送失败:%w", url, err)
+	}
+	defer resp.Body.Close()
+	if !statusAccepted(t.HTTP, resp.StatusCode) {
+		return nil, fmt.Errorf("%s 响应错误: %d", url, resp.StatusCode)
+	}
+	body, err := readLimited(resp.Body, maxBodySize)
+	if err != nil {
+		return nil, fmt.Errorf("%s 读取响应失败:%w", url, err)
+	}
+	return body, nil
+}
+
+// errBodyTooLarge 响应体超过 maxBodySize，不返回截断的内容，避免截断后的哈希被当作内容变化
+var errBodyTooLarge = fmt.Errorf("响应体超过 %d MB", maxBodySize>>20)
+
+// readLimited 最多读取 limit 字节，超出时返回 errBodyTooLarge
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// sameHost 判断 u 是否与目标地址同一主机
+func sameHost(target string, u *url.URL) bool {
+	tu, err := url.Parse(target)
+	return err == nil && tu.Host == u.Host
+}
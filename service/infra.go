@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"store/common"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInfraInterval = time.Hour
+	defaultExpiryDays    = 14
+)
+
+var (
+	infraMu sync.Mutex
+	// 主机 -> 上次成功完成基础设施检查的时间，同一主机的多个目标共用
+	infraChecked = map[string]time.Time{}
+	// 正在检查的主机，避免多个目标同时检查同一主机
+	infraRunning = map[string]bool{}
+)
+
+// CertInfo 站点证书的关键信息
+type CertInfo struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	SANs        []string  `json:"sans"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint"`
+	VerifyError string    `json:"verify_error,omitempty"`
+}
+
+// InfraSnapshot 一个主机的 DNS 记录和证书
+type InfraSnapshot struct {
+	A        []string  `json:"a"`
+	AAAA     []string  `json:"aaaa"`
+	CNAME    string    `json:"cname,omitempty"`
+	NS       []string  `json:"ns"`
+	MX       []string  `json:"mx"`
+	Cert     *CertInfo `json:"cert,omitempty"`
+	Expiring bool      `json:"expiring,omitempty"` // 证书已进入即将过期告警范围
+}
+
+// resolver 返回配置的 DNS 服务器对应的解析器，未配置时使用系统解析
+func resolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// notFound 判断是否为“没有该类记录”，这类错误记为空而不是检查失败
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// lookupDNS 查询 A/AAAA/CNAME/NS/MX 记录，结果排序以便比较
+func lookupDNS(ctx context.Context, r *net.Resolver, host string) (*InfraSnapshot, error) {
+	snap := &InfraSnapshot{}
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil && !notFound(err) {
+		return nil, fmt.Errorf("查询 A/AAAA 失败: %w", err)
+	}
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			snap.A = append(snap.A, a.IP.String())
+		} else {
+			snap.AAAA = append(snap.AAAA, a.IP.String())
+		}
+	}
+	cname, err := r.LookupCNAME(ctx, host)
+	if err != nil && !notFound(err) {
+		return nil, fmt.Errorf("查询 CNAME 失败: %w", err)
+	}
+	// 没有 CNAME 时返回主机名本身
+	if cname = strings.TrimSuffix(cname, "."); !strings.EqualFold(cname, host) {
+		snap.CNAME = cname
+	}
+	ns, err := r.LookupNS(ctx, dnsZone(host))
+	if err != nil && !notFound(err) {
+		return nil, fmt.Errorf("查询 NS 失败: %w", err)
+	}
+	for _, n := range ns {
+		snap.NS = append(snap.NS, strings.TrimSuffix(n.Host, "."))
+	}
+	mx, err := r.LookupMX(ctx, dnsZone(host))
+	if err != nil && !notFound(err) {
+		return nil, fmt.Errorf("查询 MX 失败: %w", err)
+	}
+	for _, m := range mx {
+		snap.MX = append(snap.MX, fmt.Sprintf("%d %s", m.Pref, strings.TrimSuffix(m.Host, ".")))
+	}
+	for _, list := range [][]string{snap.A, snap.AAAA, snap.NS, snap.MX} {
+		sort.Strings(list)
+	}
+	return snap, nil
+}
+
+// dnsZone NS、MX 记录挂在可注册域名上（www.bkokfi.com -> bkokfi.com）
+func dnsZone(host string) string {
+	return siteOf(host)
+}
+
+// fetchCert 连接 addr 读取证书，校验失败不算错误，只记录在 VerifyError 中
+func fetchCert(ctx context.Context, r *net.Resolver, host, addr string, roots *x509.CertPool) (*CertInfo, error) {
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second, Resolver: r},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("TLS 连接失败: %w", err)
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("服务器没有返回证书")
+	}
+
+	leaf := certs[0]
+	sum := sha256.Sum256(leaf.Raw)
+	info := &CertInfo{
+		Subject:     leaf.Subject.CommonName,
+		Issuer:      leaf.Issuer.String(),
+		SANs:        slices.Sorted(slices.Values(leaf.DNSNames)),
+		NotAfter:    leaf.NotAfter,
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}
+	inter := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		inter.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: inter}); err != nil {
+		info.VerifyError = err.Error()
+	}
+	return info, nil
+}
+
+// diffInfra 比较两次基础设施快照
+func diffInfra(old, cur *InfraSnapshot) []string {
+	var out []string
+	list := func(name string, a, b []string) {
+		if !slices.Equal(a, b) {
+			out = append(out, fmt.Sprintf("%s 记录变化: %s → %s", name, joinOrNone(a), joinOrNone(b)))
+		}
+	}
+	list("A", old.A, cur.A)
+	list("AAAA", old.AAAA, cur.AAAA)
+	if old.CNAME != cur.CNAME {
+		out = append(out, fmt.Sprintf("CNAME 变化: %s → %s", orNone(old.CNAME), orNone(cur.CNAME)))
+	}
+	list("NS", old.NS, cur.NS)
+	list("MX", old.MX, cur.MX)
+
+	oc, cc := old.Cert, cur.Cert
+	switch {
+	case oc == nil || cc == nil:
+	case oc.Fingerprint != cc.Fingerprint:
+		out = append(out, fmt.Sprintf("证书已更换: 签发者 %s, 有效期至 %s", cc.Issuer, cc.NotAfter.Format("2006-01-02")))
+		if oc.Issuer != cc.Issuer {
+			out = append(out, fmt.Sprintf("证书签发者变化: %s → %s", oc.Issuer, cc.Issuer))
+		}
+		if !slices.Equal(oc.SANs, cc.SANs) {
+			out = append(out, fmt.Sprintf("证书域名变化: %s → %s", joinOrNone(oc.SANs), joinOrNone(cc.SANs)))
+		}
+	}
+	if cc != nil && cc.VerifyError != "" && (oc == nil || oc.VerifyError == "") {
+		out = append(out, "证书校验失败: "+cc.VerifyError)
+	}
+	return out
+}
+
+func joinOrNone(list []string) string {
+	if len(list) == 0 {
+		return "(无)"
+	}
+	return strings.Join(list, ", ")
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(无)"
+	}
+	return s
+}
+
+// checkInfra 按 Infra.Interval 检查目标主机的 DNS 记录和证书，与上次结果比较，
+// 首次运行只记录；证书进入即将过期范围时告警一次
+func checkInfra(ctx context.Context, t common.Target) ([]string, error) {
+	cfg := t.Infra
+	u, err := url.Parse(t.URL)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("%s 无法解析主机名", t.URL)
+	}
+	host := u.Hostname()
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultInfraInterval
+	}
+	infraMu.Lock()
+	if infraRunning[host] || time.Since(infraChecked[host]) < interval {
+		infraMu.Unlock()
+		return nil, nil
+	}
+	infraRunning[host] = true
+	infraMu.Unlock()
+	defer func() {
+		infraMu.Lock()
+		delete(infraRunning, host)
+		infraMu.Unlock()
+	}()
+
+	r := resolver(cfg.Resolver)
+	cur, err := lookupDNS(ctx, r, host)
+	if err != nil {
+		return nil, fmt.Errorf("%s DNS 检查失败: %w", host, err)
+	}
+	if u.Scheme == "https" {
+		port := u.Port()
+		if port == "" {
+			port = "443"
+		}
+		roots, err := certPool(t.HTTP.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if cur.Cert, err = fetchCert(ctx, r, host, net.JoinHostPort(host, port), roots); err != nil {
+			return nil, fmt.Errorf("%s 证书检查失败: %w", host, err)
+		}
+	}
+
+	days := cfg.ExpiryDays
+	if days <= 0 {
+		days = defaultExpiryDays
+	}
+	var expiryAlert string
+	if cur.Cert != nil {
+		left := time.Until(cur.Cert.NotAfter)
+		cur.Expiring = left < time.Duration(days)*24*time.Hour
+		if cur.Expiring {
+			expiryAlert = fmt.Sprintf("证书将在 %.0f 天后过期 (%s)", left.Hours()/24, cur.Cert.NotAfter.Format("2006-01-02 15:04"))
+		}
+	}
+
+	var old InfraSnapshot
+	ok, err := loadState("infra", host, &old)
+	if err != nil {
+		return nil, fmt.Errorf("%s 读取基础设施记录失败: %w", host, err)
+	}
+	if err := saveState("infra", host, cur); err != nil {
+		return nil, fmt.Errorf("%s 保存基础设施记录失败: %w", host, err)
+	}
+	// 失败的检查不计入间隔，下次检查时重试
+	infraMu.Lock()
+	infraChecked[host] = time.Now()
+	infraMu.Unlock()
+	var changes []string
+	if ok {
+		changes = diffInfra(&old, cur)
+	}
+	if expiryAlert != "" && !old.Expiring {
+		changes = append(changes, expiryAlert)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	log.Printf("%s 基础设施变化: %s", host, strings.Join(changes, "; "))
+	return []string{formatChanges(host+" 基础设施变化", changes)}, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/pem"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"store/common"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dnsStub 本地 UDP DNS 服务，按 "名称|类型" 返回预设记录
+type dnsStub struct {
+	mu      sync.Mutex
+	records map[string][]dnsmessage.ResourceBody
+}
+
+func (s *dnsStub) set(name string, typ dnsmessage.Type, bodies ...dnsmessage.ResourceBody) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[name+"|"+typ.String()] = bodies
+}
+
+func startDNSStub(t *testing.T) (*dnsStub, string) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pc.Close() })
+	stub := &dnsStub{records: map[string][]dnsmessage.ResourceBody{}}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			msg := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true},
+				Questions: []dnsmessage.Question{q},
+			}
+			stub.mu.Lock()
+			for _, body := range stub.records[q.Name.String()+"|"+q.Type.String()] {
+				msg.Answers = append(msg.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   body,
+				})
+			}
+			stub.mu.Unlock()
+			out, err := msg.Pack()
+			if err == nil {
+				_, _ = pc.WriteTo(out, addr)
+			}
+		}
+	}()
+	return stub, pc.LocalAddr().String()
+}
+
+func TestCheckInfra(t *testing.T) {
+	useTempStateDir(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	stub, addr := startDNSStub(t)
+	stub.set("www.bkokfi.test.", dnsmessage.TypeA, &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+	stub.set("bkokfi.test.", dnsmessage.TypeNS, &dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.host.test.")})
+	stub.set("bkokfi.test.", dnsmessage.TypeMX, &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.bkokfi.test.")})
+
+	// 信任测试服务器的自签名证书，证书域名与主机名不符，记录为校验失败
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := common.Target{
+		URL:   "https://www.bkokfi.test:" + port + "/",
+		HTTP:  common.HTTPConfig{CAFile: caFile},
+		Infra: &common.Infra{Interval: time.Nanosecond, ExpiryDays: 365 * 100, Resolver: addr},
+	}
+
+	ctx := context.Background()
+	alerts, err := checkInfra(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 首次检查不比较，但证书已在过期告警范围内
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "证书将在") {
+		t.Fatalf("alerts = %q", alerts)
+	}
+
+	var snap InfraSnapshot
+	if _, err := loadState("infra", "www.bkokfi.test", &snap); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(snap.A, []string{"127.0.0.1"}) || !reflect.DeepEqual(snap.NS, []string{"ns1.host.test"}) ||
+		!reflect.DeepEqual(snap.MX, []string{"10 mail.bkokfi.test"}) || snap.CNAME != "" {
+		t.Errorf("snapshot = %+v", snap)
+	}
+	if snap.Cert == nil || snap.Cert.VerifyError == "" || !snap.Expiring {
+		t.Errorf("cert = %+v", snap.Cert)
+	}
+
+	stub.set("bkokfi.test.", dnsmessage.TypeNS,
+		&dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns1.other.test.")},
+		&dnsmessage.NSResource{NS: dnsmessage.MustNewName("ns2.other.test.")})
+	alerts, err = checkInfra(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "NS 记录变化: ns1.host.test → ns1.other.test, ns2.other.test") ||
+		strings.Contains(alerts[0], "证书将在") {
+		t.Errorf("alerts = %q", alerts)
+	}
+}
+
+func TestDiffInfraCert(t *testing.T) {
+	old := &InfraSnapshot{Cert: &CertInfo{Issuer: "CN=R10", SANs: []string{"bkokfi.com"}, Fingerprint: "a"}}
+	cur := &InfraSnapshot{Cert: &CertInfo{Issuer: "CN=E6", SANs: []string{"bkokfi.com", "www.bkokfi.com"}, Fingerprint: "b",
+		NotAfter: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}}
+	want := []string{
+		"证书已更换: 签发者 CN=E6, 有效期至 2026-01-02",
+		"证书签发者变化: CN=R10 → CN=E6",
+		"证书域名变化: bkokfi.com → bkokfi.com, www.bkokfi.com",
+	}
+	if got := diffInfra(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckInfraRetryAfterFailure(t *testing.T) {
+	useTempStateDir(t)
+	stub, addr := startDNSStub(t)
+	stub.set("retry.bkokfi.test.", dnsmessage.TypeA, &dnsmessage.AResource{A: [4]byte{127, 0, 0, 2}})
+
+	// 解析失败的检查不计入间隔，换成可用的 DNS 后下一次检查立即执行
+	target := common.Target{
+		URL:   "http://retry.bkokfi.test/",
+		Infra: &common.Infra{Interval: time.Hour, Resolver: "127.0.0.1:1"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := checkInfra(ctx, target); err == nil {
+		t.Fatal("DNS 不可用时应返回错误")
+	}
+	target.Infra.Resolver = addr
+	if _, err := checkInfra(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	var snap InfraSnapshot
+	if ok, err := loadState("infra", "retry.bkokfi.test", &snap); err != nil || !ok {
+		t.Fatalf("失败后应重新检查并保存记录: ok=%v err=%v", ok, err)
+	}
+	if !reflect.DeepEqual(snap.A, []string{"127.0.0.2"}) {
+		t.Errorf("A = %v", snap.A)
+	}
+}
+
+func TestFetchInfraOnContentError(t *testing.T) {
+	useTempStateDir(t)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// 只有基础设施检查用的 DNS 能解析该域名，内容抓取必然失败
+	stub, addr := startDNSStub(t)
+	stub.set("down.bkokfi.test.", dnsmessage.TypeA, &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}})
+	target := common.Target{
+		URL:   "https://down.bkokfi.test:" + port + "/",
+		Mode:  common.ModeStatic,
+		Infra: &common.Infra{Interval: time.Nanosecond, ExpiryDays: 365 * 100, Resolver: addr},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := fetch(ctx, nil, target)
+	if err == nil {
+		t.Fatal("期望内容抓取失败")
+	}
+	if len(res.alerts) != 1 || !strings.Contains(res.alerts[0], "证书将在") {
+		t.Errorf("抓取失败时也应返回基础设施告警, alerts = %q", res.alerts)
+	}
+}
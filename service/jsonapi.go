@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"store/common"
+	"strconv"
+	"strings"
+)
+
+// selectJSON 按路径表达式选取字段，支持 gjson / JSONPath 常见写法：
+// a.b.c、a.0.b、a[0].b、a.#.b 或 a[*].b（遍历数组）、a.*（遍历对象），可带 $. 前缀
+func selectJSON(doc any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var toks []string
+	for _, t := range strings.Split(path, ".") {
+		if t != "" {
+			toks = append(toks, t)
+		}
+	}
+	return selectTokens(doc, toks)
+}
+
+func selectTokens(v any, toks []string) (any, bool) {
+	if len(toks) == 0 {
+		return v, true
+	}
+	tok, rest := toks[0], toks[1:]
+	switch node := v.(type) {
+	case map[string]any:
+		if tok == "*" || tok == "#" {
+			out := map[string]any{}
+			for k, child := range node {
+				if r, ok := selectTokens(child, rest); ok {
+					out[k] = r
+				}
+			}
+			return out, true
+		}
+		child, ok := node[tok]
+		if !ok {
+			return nil, false
+		}
+		return selectTokens(child, rest)
+	case []any:
+		if tok == "*" || tok == "#" {
+			out := make([]any, 0, len(node))
+			for _, child := range node {
+				if r, ok := selectTokens(child, rest); ok {
+					out = append(out, r)
+				}
+			}
+			return out, true
+		}
+		i, err := strconv.Atoi(tok)
+		if err != nil || i < 0 || i >= len(node) {
+			return nil, false
+		}
+		return selectTokens(node[i], rest)
+	}
+	return nil, false
+}
+
+// flattenJSON 把选中的值展开成 叶子路径 -> JSON 值，用于字段级比较
+func flattenJSON(prefix string, v any, out map[string]string) {
+	switch node := v.(type) {
+	case map[string]any:
+		if len(node) == 0 {
+			out[prefix] = "{}"
+		}
+		for k, child := range node {
+			flattenJSON(prefix+"."+k, child, out)
+		}
+	case []any:
+		if len(node) == 0 {
+			out[prefix] = "[]"
+		}
+		for i, child := range node {
+			flattenJSON(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	default:
+		b, _ := json.Marshal(node)
+		out[prefix] = string(b)
+	}
+}
+
+// FieldChange 一个字段的变化，新增时 Old 为空，删除时 New 为空
+type FieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+func (c FieldChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s: 新增 %s", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s: 删除 (原值 %s)", c.Path, c.Old)
+	}
+	return fmt.Sprintf("%s: %s → %s", c.Path, c.Old, c.New)
+}
+
+// diffFields 比较两次展开后的字段，按路径排序
+func diffFields(old, cur map[string]string) []FieldChange {
+	var out []FieldChange
+	for p, v := range cur {
+		if ov, ok := old[p]; !ok || ov != v {
+			out = append(out, FieldChange{Path: p, Old: ov, New: v})
+		}
+	}
+	for p, ov := range old {
+		if _, ok := cur[p]; !ok {
+			out = append(out, FieldChange{Path: p, Old: ov})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// jsonHash 请求 JSON 接口，按配置的路径选取字段，规范化（键排序）后计算哈希，并与上次结果逐字段比较
+func jsonHash(ctx context.Context, t common.Target) (checkResult, error) {
+	url := t.URL
+	body, err := fetchBody(ctx, t, url, "application/json")
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	// UseNumber 保留数字原样，避免大整数精度丢失
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return checkResult{}, fmt.Errorf("%s 解析 JSON 失败:%w", url, err)
+	}
+
+	// 未配置路径时监控整个响应
+	selected := map[string]any{}
+	if len(t.JSONPaths) == 0 {
+		selected["$"] = doc
+	}
+	for _, p := range t.JSONPaths {
+		if v, ok := selectJSON(doc, p); ok {
+			selected[p] = v
+		}
+	}
+
+	// encoding/json 输出 map 时按键排序，得到与原始键顺序无关的规范化结果
+	canonical, err := json.MarshalIndent(selected, "", "  ")
+	if err != nil {
+		return checkResult{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	res := checkResult{text: string(canonical), hash: hex.EncodeToString(sum[:])}
+
+	fields := map[string]string{}
+	for p, v := range selected {
+		flattenJSON(p, v, fields)
+	}
+	var old map[string]string
+	ok, err := loadState("json", url, &old)
+	if err != nil {
+		return res, fmt.Errorf("%s 读取字段记录失败: %w", url, err)
+	}
+	if err := saveState("json", url, fields); err != nil {
+		return res, fmt.Errorf("%s 保存字段记录失败: %w", url, err)
+	}
+	if ok {
+		for _, c := range diffFields(old, fields) {
+			res.changes = append(res.changes, c.String())
+		}
+	}
+	return res, nil
+}
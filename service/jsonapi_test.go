@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"store/common"
+	"testing"
+)
+
+func TestSelectJSON(t *testing.T) {
+	doc := map[string]any{
+		"data": map[string]any{
+			"items": []any{
+				map[string]any{"id": "a", "stock": 1.0},
+				map[string]any{"id": "b", "stock": 0.0},
+			},
+		},
+	}
+	cases := []struct {
+		path string
+		want any
+	}{
+		{"data.items.0.id", "a"},
+		{"$.data.items[1].stock", 0.0},
+		{"data.items.#.id", []any{"a", "b"}},
+		{"data.items[*].stock", []any{1.0, 0.0}},
+	}
+	for _, c := range cases {
+		got, ok := selectJSON(doc, c.path)
+		if !ok || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s = %v (%v), want %v", c.path, got, ok, c.want)
+		}
+	}
+	if _, ok := selectJSON(doc, "data.missing"); ok {
+		t.Error("不存在的路径应返回 false")
+	}
+}
+
+func TestJSONHash(t *testing.T) {
+	useTempStateDir(t)
+	body := `{"b": 1, "a": {"price": "9.99", "stock": 3}, "noise": 1}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL, Mode: common.ModeJSON, JSONPaths: []string{"a", "b"}}
+	first, err := jsonHash(ctx, target)
+	if err != nil || len(first.changes) != 0 {
+		t.Fatalf("首次检查: changes=%v err=%v", first.changes, err)
+	}
+
+	// 键顺序和未选中字段的变化不影响哈希
+	body = `{"noise": 2, "a": {"stock": 3, "price": "9.99"}, "b": 1}`
+	same, err := jsonHash(ctx, target)
+	if err != nil || same.hash != first.hash {
+		t.Fatalf("键顺序变化不应改变哈希: err=%v", err)
+	}
+
+	body = `{"a": {"price": "10.99", "sale": true}, "b": 1}`
+	cur, err := jsonHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur.hash == first.hash {
+		t.Fatal("字段变化后哈希应改变")
+	}
+	want := []string{
+		`a.price: "9.99" → "10.99"`,
+		"a.sale: 新增 true",
+		"a.stock: 删除 (原值 3)",
+	}
+	if !reflect.DeepEqual(cur.changes, want) {
+		t.Errorf("changes = %q, want %q", cur.changes, want)
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"store/common"
+	"strings"
+	"sync"
+)
+
+const defaultLinkConcurrency = 5
+
+// LinkStatus 一个链接的检查结果
+type LinkStatus struct {
+	Status int    `json:"status,omitempty"`
+	Final  string `json:"final,omitempty"` // 跟随重定向后的地址，没有跳转时为空
+	Error  string `json:"error,omitempty"`
+}
+
+func (s LinkStatus) broken() bool {
+	return s.Error != "" || s.Status >= 400
+}
+
+func (s LinkStatus) String() string {
+	if s.Error != "" {
+		return s.Error
+	}
+	return fmt.Sprintf("HTTP %d", s.Status)
+}
+
+// extractLinks 提取页面中匹配 selector 的 http(s) 链接，转换为绝对地址并去重
+func extractLinks(page string, body []byte, selector string) ([]string, error) {
+	base, err := url.Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			href, ok = s.Attr("src")
+		}
+		if !ok || strings.TrimSpace(href) == "" {
+			return
+		}
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		seen[u.String()] = true
+	})
+	links := make([]string, 0, len(seen))
+	for l := range seen {
+		links = append(links, l)
+	}
+	sort.Strings(links)
+	return links, nil
+}
+
+// checkLink 请求链接并跟随重定向，优先用 HEAD，服务器不支持时改用 GET
+func checkLink(ctx context.Context, client *http.Client, t common.Target, link string) LinkStatus {
+	do := func(method string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", common.UserAgent)
+		// 认证信息只发给目标自己的域名
+		if sameHost(t.URL, req.URL) {
+			applyHTTPConfig(req, t.HTTP)
+		}
+		return client.Do(req)
+	}
+
+	resp, err := do(http.MethodHead)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented ||
+		resp.StatusCode == http.StatusForbidden) {
+		resp.Body.Close()
+		resp, err = do(http.MethodGet)
+	}
+	if err != nil {
+		return LinkStatus{Error: shortError(err)}
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	st := LinkStatus{Status: resp.StatusCode}
+	if final := resp.Request.URL.String(); final != link {
+		st.Final = final
+	}
+	return st
+}
+
+// shortError 去掉 url.Error 中重复的方法和地址
+func shortError(err error) string {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return ue.Err.Error()
+	}
+	return err.Error()
+}
+
+// checkLinks 并发检查所有链接，同时进行的请求不超过 concurrency
+func checkLinks(ctx context.Context, t common.Target, links []string, concurrency int) (map[string]LinkStatus, error) {
+	client, err := linkClient(t)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = defaultLinkConcurrency
+	}
+	var (
+		wg      sync.WaitGroup
+		resMu   sync.Mutex
+		results = make(map[string]LinkStatus, len(links))
+		sem     = make(chan struct{}, concurrency)
+	)
+	for _, link := range links {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			st := checkLink(ctx, client, t, link)
+			resMu.Lock()
+			results[link] = st
+			resMu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return results, nil
+}
+
+// diffLinks 比较两次检查结果：链接增减、开始跳转或跳转目标变化、失效和恢复
+func diffLinks(old, cur map[string]LinkStatus) []string {
+	var added, removed, changed []string
+	for link, st := range cur {
+		prev, ok := old[link]
+		if !ok {
+			line := "新增链接: " + link
+			if st.broken() {
+				line += " (已失效: " + st.String() + ")"
+			}
+			added = append(added, line)
+			continue
+		}
+		switch {
+		case st.broken() && !prev.broken():
+			changed = append(changed, fmt.Sprintf("链接失效: %s (%s)", link, st))
+		case !st.broken() && prev.broken():
+			changed = append(changed, "链接恢复: "+link)
+		case st.Final != prev.Final && st.Final != "":
+			if prev.Final == "" {
+				changed = append(changed, fmt.Sprintf("开始跳转: %s → %s", link, st.Final))
+			} else {
+				changed = append(changed, fmt.Sprintf("跳转目标变化: %s → %s (原 %s)", link, st.Final, prev.Final))
+			}
+		case st.Final != prev.Final:
+			changed = append(changed, "不再跳转: "+link)
+		}
+	}
+	for link := range old {
+		if _, ok := cur[link]; !ok {
+			removed = append(removed, "删除链接: "+link)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return append(append(changed, added...), removed...)
+}
+
+// linksHash 提取页面链接并检查状态，哈希覆盖链接列表、跳转目标和是否失效
+func linksHash(ctx context.Context, t common.Target) (checkResult, error) {
+	url := t.URL
+	cfg := common.LinkCheck{}
+	if t.Links != nil {
+		cfg = *t.Links
+	}
+	selector := cfg.Selector
+	if selector == "" {
+		selector = "a[href]"
+	}
+
+	body, err := fetchBody(ctx, t, url, "text/html")
+	if err != nil {
+		return checkResult{}, err
+	}
+	links, err := extractLinks(url, body, selector)
+	if err != nil {
+		return checkResult{}, fmt.Errorf("%s 解析 HTML 失败:%w", url, err)
+	}
+	exclude, err := compilePatterns(cfg.Exclude)
+	if err != nil {
+		return checkResult{}, fmt.Errorf("%s 链接检查配置错误: %w", url, err)
+	}
+	links = slices.DeleteFunc(links, func(link string) bool {
+		return slices.ContainsFunc(exclude, func(re *regexp.Regexp) bool { return re.MatchString(link) })
+	})
+
+	cur, err := checkLinks(ctx, t, links, cfg.Concurrency)
+	if err != nil {
+		return checkResult{}, err
+	}
+	var sb strings.Builder
+	for _, link := range links {
+		st := cur[link]
+		state := "ok"
+		if st.broken() {
+			state = "broken"
+		}
+		fmt.Fprintf(&sb, "%s\t%s\t%s\n", link, state, st.Final)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	res := checkResult{text: sb.String(), hash: hex.EncodeToString(sum[:])}
+
+	var old map[string]LinkStatus
+	ok, err := loadState("links", url, &old)
+	if err != nil {
+		return res, fmt.Errorf("%s 读取链接记录失败: %w", url, err)
+	}
+	if err := saveState("links", url, cur); err != nil {
+		return res, fmt.Errorf("%s 保存链接记录失败: %w", url, err)
+	}
+	if ok {
+		res.changes = diffLinks(old, cur)
+	}
+	return res, nil
+}
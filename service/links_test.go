@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"store/common"
+	"testing"
+)
+
+func TestLinksHash(t *testing.T) {
+	useTempStateDir(t)
+	page := `<a href="/ok">ok</a><a href="/signs.pdf">signs</a><a href="/donate">donate</a><a href="mailto:x@y">mail</a><a href="/share?u=1">share</a>`
+	var headOnly bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_, _ = w.Write([]byte("<html><body>" + page + "</body></html>"))
+		case "/ok":
+			if headOnly && r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/donate":
+			if headOnly {
+				http.Redirect(w, r, "/new-donate", http.StatusFound)
+			}
+		case "/new-donate":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL + "/", Mode: common.ModeLinks, Links: &common.LinkCheck{Exclude: []string{`/share`}}}
+	first, err := linksHash(ctx, target)
+	if err != nil || len(first.changes) != 0 {
+		t.Fatalf("首次检查只记录: %+v err=%v", first, err)
+	}
+
+	// HEAD 不支持时改用 GET，/ok 仍然正常
+	headOnly = true
+	page = `<a href="/ok">ok</a><a href="/donate">donate</a><a href="/new">new</a>`
+	res, err := linksHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"开始跳转: " + srv.URL + "/donate → " + srv.URL + "/new-donate",
+		"新增链接: " + srv.URL + "/new (已失效: HTTP 404)",
+		"删除链接: " + srv.URL + "/signs.pdf",
+	}
+	if !reflect.DeepEqual(res.changes, want) {
+		t.Errorf("changes = %q\nwant %q", res.changes, want)
+	}
+}
+
+func TestDiffLinksBroken(t *testing.T) {
+	old := map[string]LinkStatus{"a": {Status: 200}, "b": {Error: "timeout"}}
+	cur := map[string]LinkStatus{"a": {Status: 500}, "b": {Status: 200}}
+	want := []string{"链接失效: a (HTTP 500)", "链接恢复: b"}
+	if got := diffLinks(old, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckLinksFollowRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// 目标本身不跟随重定向，链接检查仍要拿到跳转后的最终状态
+	target := common.Target{URL: srv.URL + "/", HTTP: common.HTTPConfig{MaxRedirects: -1}}
+	t.Cleanup(func() { dropClient(target) })
+	got, err := checkLinks(context.Background(), target, []string{srv.URL + "/old"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := got[srv.URL+"/old"]
+	if st.Status != http.StatusOK || st.Final != srv.URL+"/new" {
+		t.Errorf("链接状态 = %+v, 期望跟随跳转到 /new 且为 200", st)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+)
+
+// extractMeta 提取页面 <head> 中的元数据：title、meta 标签（description、robots、Open Graph、Twitter 等）、
+// canonical 和 JSON-LD。值统一保存为 JSON，空字符串也能和字段不存在区分开
+func extractMeta(doc *goquery.Document) map[string]string {
+	fields := map[string]string{}
+	set := func(key, value string) {
+		b, _ := json.Marshal(strings.Join(strings.Fields(value), " "))
+		// 同名标签出现多次时（如多张 og:image）按顺序编号
+		k := key
+		for i := 1; ; i++ {
+			if _, ok := fields[k]; !ok {
+				break
+			}
+			k = fmt.Sprintf("%s[%d]", key, i)
+		}
+		fields[k] = string(b)
+	}
+
+	if title := doc.Find("head title").First(); title.Length() > 0 {
+		set("title", title.Text())
+	}
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		name := s.AttrOr("property", s.AttrOr("name", s.AttrOr("http-equiv", "")))
+		content, ok := s.Attr("content")
+		if name == "" || !ok {
+			return
+		}
+		name = strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(name, "og:"), strings.HasPrefix(name, "twitter:"),
+			strings.HasPrefix(name, "product:"), strings.HasPrefix(name, "article:"):
+			set(name, content)
+		default:
+			set("meta:"+name, content)
+		}
+	})
+	doc.Find(`link[rel="canonical"]`).Each(func(_ int, s *goquery.Selection) {
+		set("canonical", s.AttrOr("href", ""))
+	})
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		prefix := fmt.Sprintf("jsonld[%d]", i)
+		dec := json.NewDecoder(strings.NewReader(s.Text()))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			// 无法解析时按原文比较
+			set(prefix, s.Text())
+			return
+		}
+		flattenJSON(prefix, v, fields)
+	})
+	return fields
+}
+
+// checkMeta 提取页面元数据，逐字段与上次比较，有变化时返回告警
+func checkMeta(target, page string) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return nil, fmt.Errorf("%s 解析 HTML 失败:%w", target, err)
+	}
+	fields := extractMeta(doc)
+
+	var old map[string]string
+	ok, err := loadState("meta", target, &old)
+	if err != nil {
+		return nil, fmt.Errorf("%s 读取元数据记录失败: %w", target, err)
+	}
+	if err := saveState("meta", target, fields); err != nil {
+		return nil, fmt.Errorf("%s 保存元数据记录失败: %w", target, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	var changes []string
+	for _, c := range diffFields(old, fields) {
+		changes = append(changes, c.String())
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return []string{formatChanges(target+" 元数据变化", changes)}, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/http/httptest"
+	"store/common"
+	"strings"
+	"testing"
+)
+
+func TestCheckMeta(t *testing.T) {
+	useTempStateDir(t)
+	page := `<html><head><title>Patriot Shop</title>
+<meta name="description" content="Official store">
+<meta name="robots" content="index, follow">
+<meta property="og:image" content="https://cdn/a.png">
+<meta property="og:image" content="https://cdn/b.png">
+<link rel="canonical" href="https://store.gavinnewsom.com/the-patriot-shop/">
+<script type="application/ld+json">{"@type": "Product", "offers": {"price": "30.00"}}</script>
+</head><body><p>hello</p></body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL, Mode: common.ModeStatic, Meta: true}
+	first, err := fetch(ctx, nil, target)
+	if err != nil || len(first.alerts) != 0 {
+		t.Fatalf("首次检查只记录: alerts=%q err=%v", first.alerts, err)
+	}
+	fields := extractMeta(mustParse(t, page))
+	for k, want := range map[string]string{"og:image[1]": `"https://cdn/b.png"`, "jsonld[0].offers.price": `"30.00"`, "meta:robots": `"index, follow"`} {
+		if fields[k] != want {
+			t.Errorf("%s = %s, 期望 %s", k, fields[k], want)
+		}
+	}
+
+	// 正文变化只影响内容哈希，不产生元数据告警
+	page = strings.Replace(page, "<p>hello</p>", "<p>bye</p>", 1)
+	if same, err := fetch(ctx, nil, target); err != nil || len(same.alerts) != 0 {
+		t.Fatalf("正文变化不应产生元数据告警: alerts=%q err=%v", same.alerts, err)
+	}
+
+	page = strings.Replace(page, `"30.00"`, `"35.00"`, 1)
+	page = strings.Replace(page, "index, follow", "noindex", 1)
+	page = strings.Replace(page, `<meta name="description" content="Official store">`, "", 1)
+	res, err := fetch(ctx, nil, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{srv.URL + " 元数据变化",
+		`jsonld[0].offers.price: &#34;30.00&#34; → &#34;35.00&#34;`,
+		`meta:description: 删除 (原值 &#34;Official store&#34;)`,
+		`meta:robots: &#34;index, follow&#34; → &#34;noindex&#34;`,
+	}
+	if len(res.alerts) != 1 {
+		t.Fatalf("期望 1 条元数据告警, 实际 %q", res.alerts)
+	}
+	for _, w := range want {
+		if !strings.Contains(res.alerts[0], w) {
+			t.Errorf("告警缺少 %q:\n%s", w, res.alerts[0])
+		}
+	}
+}
+
+func mustParse(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"math"
+	"regexp"
+	"store/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 每个序列最多保留的点数，超过后丢弃最早的
+	maxSeriesPoints = 10000
+	// 数值不变时也至少隔这么久记录一个点，让曲线能看出检查仍在进行
+	seriesHeartbeat = time.Hour
+)
+
+// 默认的数值正则：可带负号、千分位逗号和小数
+var defaultNumberRe = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+
+// Point 时间序列中的一个点
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// seriesState 一个目标所有提取项的时间序列，以及各提取项当前越过的阈值（above / below）
+type seriesState struct {
+	Series map[string][]Point `json:"series"`
+	Breach map[string]string  `json:"breach,omitempty"`
+}
+
+// validateExtractors 检查提取项名称是否为空或重复，正则能否编译
+func validateExtractors(mode string, extract []common.Extractor) error {
+	seen := map[string]bool{}
+	for _, e := range extract {
+		if e.Name == "" {
+			return errors.New("提取项缺少 Name")
+		}
+		if e.Selector != "" && mode != common.ModeStatic && mode != common.ModeDynamic {
+			return fmt.Errorf("提取项 %s: %s 模式没有 HTML，不能使用 Selector", e.Name, mode)
+		}
+		if seen[e.Name] {
+			return fmt.Errorf("提取项名称重复: %s", e.Name)
+		}
+		seen[e.Name] = true
+		if e.Regex != "" {
+			if _, err := regexp.Compile(e.Regex); err != nil {
+				return fmt.Errorf("提取项 %s: %w", e.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// extractValue 按 Selector 取元素文本（未配置时用整页文本），再用正则取出数值；
+// 正则有分组时取第一个分组
+func extractValue(html, text string, e common.Extractor) (float64, error) {
+	src := text
+	if e.Selector != "" {
+		if html == "" {
+			return 0, errors.New("当前抓取方式没有 HTML，不能使用 Selector")
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		if err != nil {
+			return 0, err
+		}
+		sel := doc.Find(e.Selector).First()
+		if sel.Length() == 0 {
+			return 0, fmt.Errorf("没有匹配 %s 的元素", e.Selector)
+		}
+		src = strings.Join(strings.Fields(sel.Text()), " ")
+	}
+
+	re := defaultNumberRe
+	if e.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(e.Regex); err != nil {
+			return 0, err
+		}
+	}
+	m := re.FindStringSubmatch(src)
+	if m == nil {
+		return 0, fmt.Errorf("没有找到数值: %q", truncate(src, 80))
+	}
+	raw := m[0]
+	if len(m) > 1 {
+		raw = m[1]
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(raw), ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析数值 %q: %w", raw, err)
+	}
+	return v, nil
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
+
+// breachOf 返回数值越过的阈值
+func breachOf(e common.Extractor, v float64) string {
+	switch {
+	case e.Above != nil && v > *e.Above:
+		return "above"
+	case e.Below != nil && v < *e.Below:
+		return "below"
+	}
+	return ""
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// recordMetrics 提取各数值并追加到时间序列，检查阈值（状态变化时告警）和相对上一次的变化幅度
+func recordMetrics(target string, extract []common.Extractor, res checkResult) ([]string, error) {
+	var st seriesState
+	if _, err := loadState("series", target, &st); err != nil {
+		return nil, fmt.Errorf("%s 读取时间序列失败: %w", target, err)
+	}
+	if st.Series == nil {
+		st.Series = map[string][]Point{}
+	}
+	if st.Breach == nil {
+		st.Breach = map[string]string{}
+	}
+
+	now := time.Now()
+	var changes []string
+	var errs []error
+	for _, e := range extract {
+		v, err := extractValue(res.html, res.text, e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s 提取 %s 失败: %w", target, e.Name, err))
+			continue
+		}
+		points := st.Series[e.Name]
+		var last *Point
+		if len(points) > 0 {
+			last = &points[len(points)-1]
+		}
+
+		if last != nil && e.ChangePercent > 0 && v != last.Value {
+			pct := math.Inf(1)
+			if last.Value != 0 {
+				pct = math.Abs(v-last.Value) / math.Abs(last.Value) * 100
+			}
+			if pct > e.ChangePercent {
+				changes = append(changes, fmt.Sprintf("%s 变化 %.1f%%: %s → %s", e.Name, pct, formatValue(last.Value), formatValue(v)))
+			}
+		}
+		if breach := breachOf(e, v); breach != st.Breach[e.Name] {
+			switch breach {
+			case "above":
+				changes = append(changes, fmt.Sprintf("%s 高于 %s: %s", e.Name, formatValue(*e.Above), formatValue(v)))
+			case "below":
+				changes = append(changes, fmt.Sprintf("%s 低于 %s: %s", e.Name, formatValue(*e.Below), formatValue(v)))
+			default:
+				changes = append(changes, fmt.Sprintf("%s 回到阈值范围内: %s", e.Name, formatValue(v)))
+			}
+			st.Breach[e.Name] = breach
+		}
+
+		if last == nil || last.Value != v || now.Sub(last.Time) >= seriesHeartbeat {
+			points = append(points, Point{Time: now, Value: v})
+			if len(points) > maxSeriesPoints {
+				points = points[len(points)-maxSeriesPoints:]
+			}
+			st.Series[e.Name] = points
+		}
+	}
+	if err := saveState("series", target, st); err != nil {
+		return nil, fmt.Errorf("%s 保存时间序列失败: %w", target, err)
+	}
+	var alerts []string
+	if len(changes) > 0 {
+		alerts = append(alerts, formatChanges(target+" 数值告警", changes))
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// writeSeriesCSV 以 name,time,value 三列输出时间序列
+func writeSeriesCSV(w io.Writer, series map[string][]Point, names []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"name", "time", "value"}); err != nil {
+		return err
+	}
+	for _, name := range names {
+		for _, p := range series[name] {
+			if err := cw.Write([]string{name, p.Time.Format(time.RFC3339), formatValue(p.Value)}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
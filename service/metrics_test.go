@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"store/common"
+	"strings"
+	"testing"
+)
+
+func TestExtractValue(t *testing.T) {
+	html := `<div class="price">Sale: $1,234.50 <s>$1,500</s></div><p>Supply 21,000,000 tokens</p>`
+	cases := []struct {
+		e    common.Extractor
+		want float64
+	}{
+		{common.Extractor{Selector: ".price"}, 1234.50},
+		{common.Extractor{Selector: "s"}, 1500},
+		{common.Extractor{Regex: `Supply ([\d,]+)`}, 21000000},
+	}
+	for _, c := range cases {
+		got, err := extractValue(html, "Sale: $1,234.50 $1,500 Supply 21,000,000 tokens", c.e)
+		if err != nil || got != c.want {
+			t.Errorf("%+v = %v (%v), want %v", c.e, got, err, c.want)
+		}
+	}
+	if _, err := extractValue(html, "", common.Extractor{Selector: ".missing"}); err == nil {
+		t.Error("没有匹配元素时应返回错误")
+	}
+}
+
+func TestRecordMetricsAndHistory(t *testing.T) {
+	useTempStateDir(t)
+	target := "https://store.gavinnewsom.com/"
+	limit := 40.0
+	extract := []common.Extractor{{Name: "hat", Selector: ".price", ChangePercent: 10, Above: &limit}}
+	page := func(price string) checkResult {
+		return checkResult{html: `<span class="price">$` + price + `</span>`}
+	}
+
+	for _, c := range []struct {
+		price string
+		want  []string
+	}{
+		{"30", nil},
+		{"32", nil},
+		{"36", []string{"hat 变化 12.5%: 32 → 36"}},
+		{"45", []string{"hat 变化 25.0%: 36 → 45", "hat 高于 40: 45"}},
+		{"44", nil},
+		{"39", []string{"hat 变化 11.4%: 44 → 39", "hat 回到阈值范围内: 39"}},
+	} {
+		alerts, err := recordMetrics(target, extract, page(c.price))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.want) == 0 && len(alerts) != 0 {
+			t.Errorf("%s: 不应告警: %q", c.price, alerts)
+		}
+		for _, w := range c.want {
+			if len(alerts) != 1 || !strings.Contains(alerts[0], w) {
+				t.Errorf("%s: alerts = %q, 缺少 %q", c.price, alerts, w)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handleHistory(rec, httptest.NewRequest("GET", "/history?url="+url.QueryEscape(target), nil))
+	var series map[string][]Point
+	if err := json.Unmarshal(rec.Body.Bytes(), &series); err != nil || len(series["hat"]) != 6 {
+		t.Fatalf("JSON: %s err=%v", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	handleHistory(rec, httptest.NewRequest("GET", "/history?format=csv&name=hat&url="+url.QueryEscape(target), nil))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 7 || lines[0] != "name,time,value" || !strings.HasPrefix(lines[1], "hat,") || !strings.HasSuffix(lines[6], ",39") {
+		t.Errorf("CSV:\n%s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handleHistory(rec, httptest.NewRequest("GET", "/history?url=https://unknown/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("未知目标应返回 404, 实际 %d", rec.Code)
+	}
+}
+
+func TestValidateExtractors(t *testing.T) {
+	price := []common.Extractor{{Name: "price", Selector: ".price"}}
+	if err := validateExtractors(common.ModeStatic, price); err != nil {
+		t.Errorf("static 模式可以使用 Selector: %v", err)
+	}
+	if err := validateExtractors(common.ModeJSON, price); err == nil {
+		t.Error("json 模式没有 HTML，Selector 应在启动时报错")
+	}
+	if err := validateExtractors(common.ModeJSON, []common.Extractor{{Name: "price", Regex: `price: (\d+)`}}); err != nil {
+		t.Errorf("只用 Regex 的提取项适用于所有模式: %v", err)
+	}
+	if err := validateExtractors(common.ModeStatic, append(price, price...)); err == nil {
+		t.Error("名称重复应返回错误")
+	}
+}
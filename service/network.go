@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"golang.org/x/net/publicsuffix"
+	"html"
+	"net/url"
+	"slices"
+	"sort"
+	"store/utils"
+	"strings"
+)
+
+// siteOf 返回域名的可注册部分（如 www.bkokfi.com -> bkokfi.com），用于区分第三方域名
+func siteOf(host string) string {
+	site, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(host))
+	if err != nil {
+		return strings.ToLower(host)
+	}
+	return site
+}
+
+// diffNetwork 比较两次页面加载的网络请求，返回新增第三方域名、脚本变化和新出现的失败请求
+func diffNetwork(target string, old, cur *utils.NetworkSnapshot) []string {
+	site := ""
+	if u, err := url.Parse(target); err == nil {
+		site = siteOf(u.Hostname())
+	}
+
+	var out []string
+	known := map[string]bool{}
+	for _, d := range old.Domains {
+		known[d] = true
+	}
+	for _, d := range cur.Domains {
+		if !known[d] && siteOf(d) != site {
+			out = append(out, "新增第三方域名: "+d)
+		}
+	}
+
+	var scripts []string
+	for u, h := range cur.Scripts {
+		switch oh, ok := old.Scripts[u]; {
+		case !ok:
+			scripts = append(scripts, "新增脚本: "+u)
+		case oh != "" && h != "" && oh != h:
+			scripts = append(scripts, "脚本内容变化: "+u)
+		}
+	}
+	sort.Strings(scripts)
+	out = append(out, scripts...)
+
+	var failed []string
+	for u, reason := range cur.Failed {
+		if _, ok := old.Failed[u]; !ok {
+			failed = append(failed, fmt.Sprintf("请求失败: %s (%s)", u, reason))
+		}
+	}
+	sort.Strings(failed)
+	return append(out, failed...)
+}
+
+// checkNetwork 与之前见过的网络请求比较并保存本次结果，首次运行只记录不告警。
+// 域名和脚本累积保存，按需加载的第三方资源不会每次出现都重复告警
+func checkNetwork(target string, snap *utils.NetworkSnapshot) ([]string, error) {
+	var old utils.NetworkSnapshot
+	ok, err := loadState("network", target, &old)
+	if err != nil {
+		return nil, fmt.Errorf("%s 读取网络请求记录失败: %w", target, err)
+	}
+	var changes []string
+	saved := *snap
+	if ok {
+		changes = diffNetwork(target, &old, snap)
+		saved = mergeNetwork(&old, snap)
+	}
+	if err := saveState("network", target, saved); err != nil {
+		return nil, fmt.Errorf("%s 保存网络请求记录失败: %w", target, err)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return []string{formatChanges(target+" 网络请求变化", changes)}, nil
+}
+
+// mergeNetwork 合并之前见过的域名和脚本，脚本哈希以本次读取成功的为准；失败请求只保留本次的
+func mergeNetwork(old, cur *utils.NetworkSnapshot) utils.NetworkSnapshot {
+	merged := utils.NetworkSnapshot{
+		Domains: slices.Clone(old.Domains),
+		Scripts: map[string]string{},
+		Failed:  cur.Failed,
+	}
+	for _, d := range cur.Domains {
+		if !slices.Contains(merged.Domains, d) {
+			merged.Domains = append(merged.Domains, d)
+		}
+	}
+	sort.Strings(merged.Domains)
+	for u, h := range old.Scripts {
+		merged.Scripts[u] = h
+	}
+	for u, h := range cur.Scripts {
+		if h != "" || merged.Scripts[u] == "" {
+			merged.Scripts[u] = h
+		}
+	}
+	return merged
+}
+
+// formatChanges 生成 HTML 格式的告警消息，过长时截断
+func formatChanges(title string, changes []string) string {
+	var sb strings.Builder
+	sb.WriteString("<b>" + html.EscapeString(title) + "</b>\n")
+	for i, c := range changes {
+		line := html.EscapeString(c) + "\n"
+		if sb.Len()+len(line) > 3900 {
+			sb.WriteString(fmt.Sprintf("… 其余 %d 项省略", len(changes)-i))
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"reflect"
+	"store/utils"
+	"testing"
+)
+
+func TestDiffNetwork(t *testing.T) {
+	old := &utils.NetworkSnapshot{
+		Domains: []string{"stopelectionrigging.com", "cdn.stopelectionrigging.com", "fonts.googleapis.com"},
+		Scripts: map[string]string{"https://stopelectionrigging.com/app.js": "a"},
+		Failed:  map[string]string{"https://stopelectionrigging.com/old.png": "HTTP 404"},
+	}
+	cur := &utils.NetworkSnapshot{
+		Domains: []string{"stopelectionrigging.com", "static.stopelectionrigging.com", "fonts.googleapis.com", "secure.actblue.com"},
+		Scripts: map[string]string{
+			"https://stopelectionrigging.com/app.js": "b",
+			"https://secure.actblue.com/embed.js":    "c",
+		},
+		Failed: map[string]string{
+			"https://stopelectionrigging.com/old.png": "HTTP 404",
+			"https://stopelectionrigging.com/api":     "HTTP 500",
+		},
+	}
+
+	got := diffNetwork("https://stopelectionrigging.com/", old, cur)
+	want := []string{
+		"新增第三方域名: secure.actblue.com",
+		"新增脚本: https://secure.actblue.com/embed.js",
+		"脚本内容变化: https://stopelectionrigging.com/app.js",
+		"请求失败: https://stopelectionrigging.com/api (HTTP 500)",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("第 %d 项 = %q, 期望 %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckNetworkSeen(t *testing.T) {
+	useTempStateDir(t)
+	const target = "https://stopelectionrigging.com/"
+	withAds := &utils.NetworkSnapshot{
+		Domains: []string{"stopelectionrigging.com", "ads.example.net"},
+		Scripts: map[string]string{
+			"https://stopelectionrigging.com/app.js": "a",
+			"https://ads.example.net/ad.js":          "x",
+		},
+	}
+	withoutAds := &utils.NetworkSnapshot{
+		Domains: []string{"stopelectionrigging.com"},
+		// 本次读取脚本内容失败，只有地址
+		Scripts: map[string]string{"https://stopelectionrigging.com/app.js": ""},
+	}
+
+	// 按需加载的第三方域名和脚本时有时无，见过一次之后不再告警；读取失败的脚本不算内容变化
+	for i, snap := range []*utils.NetworkSnapshot{withAds, withoutAds, withAds} {
+		alerts, err := checkNetwork(target, snap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 0 {
+			t.Errorf("第 %d 次检查 alerts = %q", i+1, alerts)
+		}
+	}
+
+	var saved utils.NetworkSnapshot
+	if _, err := loadState("network", target, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved.Domains, []string{"ads.example.net", "stopelectionrigging.com"}) {
+		t.Errorf("domains = %v", saved.Domains)
+	}
+	if saved.Scripts["https://stopelectionrigging.com/app.js"] != "a" {
+		t.Errorf("读取失败不应覆盖已保存的脚本哈希: %v", saved.Scripts)
+	}
+
+	changed := &utils.NetworkSnapshot{
+		Domains: []string{"stopelectionrigging.com"},
+		Scripts: map[string]string{"https://stopelectionrigging.com/app.js": "b"},
+	}
+	if alerts, err := checkNetwork(target, changed); err != nil || len(alerts) != 1 {
+		t.Errorf("脚本内容变化应告警: alerts=%q err=%v", alerts, err)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 记录的响应头，其中 securityHeaders 变化时告警
+var (
+	recordedHeaders = []string{"Server", "Cache-Control"}
+	securityHeaders = []string{
+		"Content-Security-Policy",
+		"Strict-Transport-Security",
+		"X-Frame-Options",
+		"X-Content-Type-Options",
+		"Referrer-Policy",
+		"Permissions-Policy",
+	}
+)
+
+// ResponseInfo 一次请求的响应状态、跳转链和部分响应头
+type ResponseInfo struct {
+	Status   int               `json:"status"`
+	FinalURL string            `json:"final_url"`
+	Chain    []string          `json:"chain,omitempty"` // 每一跳为 "<状态码> <地址>"，不含最终响应
+	Headers  map[string]string `json:"headers,omitempty"`
+	// Cookies Set-Cookie 中的 cookie 名；保存的记录为历次响应中出现过的所有名字，
+	// 开启 cookie jar 后服务器不再重复下发也不会误报
+	Cookies []string `json:"cookies,omitempty"`
+}
+
+// responseInfo 从最终响应回溯 resp.Request.Response 得到完整跳转链
+func responseInfo(resp *http.Response) *ResponseInfo {
+	info := &ResponseInfo{Status: resp.StatusCode, FinalURL: resp.Request.URL.String(), Headers: map[string]string{}}
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		info.Chain = append([]string{fmt.Sprintf("%d %s", r.StatusCode, r.Request.URL)}, info.Chain...)
+	}
+	for _, h := range append(slices.Clone(recordedHeaders), securityHeaders...) {
+		if v := strings.Join(resp.Header.Values(h), ", "); v != "" {
+			info.Headers[h] = v
+		}
+	}
+	for _, c := range resp.Cookies() {
+		if !slices.Contains(info.Cookies, c.Name) {
+			info.Cookies = append(info.Cookies, c.Name)
+		}
+	}
+	sort.Strings(info.Cookies)
+	return info
+}
+
+// diffResponse 比较两次响应：状态码、跳转链、安全相关响应头和新出现的 cookie 名
+func diffResponse(old, cur *ResponseInfo) []string {
+	var out []string
+	if old.Status != cur.Status {
+		out = append(out, fmt.Sprintf("状态码变化: %d → %d", old.Status, cur.Status))
+	}
+	oldChain, curChain := strings.Join(old.Chain, " → "), strings.Join(cur.Chain, " → ")
+	switch {
+	case oldChain == curChain && (len(cur.Chain) == 0 || old.FinalURL == cur.FinalURL):
+	case len(old.Chain) == 0:
+		out = append(out, fmt.Sprintf("开始跳转: %s → %s", curChain, cur.FinalURL))
+	case len(cur.Chain) == 0:
+		out = append(out, "不再跳转: "+oldChain)
+	default:
+		out = append(out, fmt.Sprintf("跳转链变化: %s → %s (原 %s → %s)", curChain, cur.FinalURL, oldChain, old.FinalURL))
+	}
+	for _, h := range securityHeaders {
+		if ov, cv := old.Headers[h], cur.Headers[h]; ov != cv {
+			out = append(out, fmt.Sprintf("响应头 %s 变化: %s → %s", h, orNone(ov), orNone(cv)))
+		}
+	}
+	var added []string
+	for _, c := range cur.Cookies {
+		if !slices.Contains(old.Cookies, c) {
+			added = append(added, c)
+		}
+	}
+	if len(added) > 0 {
+		out = append(out, "新出现的 Set-Cookie: "+strings.Join(added, ", "))
+	}
+	return out
+}
+
+// checkResponse 与上一次保存的响应信息比较并保存本次结果，首次运行只记录不告警
+func checkResponse(target string, info *ResponseInfo) ([]string, error) {
+	var old ResponseInfo
+	ok, err := loadState("response", target, &old)
+	if err != nil {
+		return nil, fmt.Errorf("%s 读取响应记录失败: %w", target, err)
+	}
+	var changes []string
+	saved := *info
+	if ok {
+		changes = diffResponse(&old, info)
+		saved.Cookies = slices.Clone(old.Cookies)
+		for _, c := range info.Cookies {
+			if !slices.Contains(saved.Cookies, c) {
+				saved.Cookies = append(saved.Cookies, c)
+			}
+		}
+		sort.Strings(saved.Cookies)
+	}
+	if err := saveState("response", target, saved); err != nil {
+		return nil, fmt.Errorf("%s 保存响应记录失败: %w", target, err)
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return []string{formatChanges(target+" 响应变化", changes)}, nil
+}
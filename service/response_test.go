@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"store/common"
+	"strings"
+	"testing"
+)
+
+func TestResponseInfo(t *testing.T) {
+	useTempStateDir(t)
+	csp := "default-src 'self'"
+	redirect := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" && redirect {
+			http.Redirect(w, r, "/landing", http.StatusMovedPermanently)
+			return
+		}
+		if r.URL.Path == "/landing" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("Content-Security-Policy", csp)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "_ga", Value: "2"})
+		_, _ = w.Write([]byte("<body>ok</body>"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	target := common.Target{URL: srv.URL + "/", Response: true}
+	_, info, err := staticHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ResponseInfo{
+		Status:   200,
+		FinalURL: srv.URL + "/",
+		Headers:  map[string]string{"Server": "nginx", "Content-Security-Policy": csp},
+		Cookies:  []string{"_ga", "session"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Fatalf("info = %+v", info)
+	}
+	if alerts, err := checkResponse(target.URL, info); err != nil || alerts != nil {
+		t.Fatalf("首次检查只记录: %q err=%v", alerts, err)
+	}
+
+	redirect, csp = true, "default-src *"
+	_, info, err = staticHash(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := diffResponse(want, info)
+	wantChanges := []string{
+		"开始跳转: 301 " + srv.URL + "/ → 302 " + srv.URL + "/landing → " + srv.URL + "/new",
+		"响应头 Content-Security-Policy 变化: default-src 'self' → default-src *",
+	}
+	if !reflect.DeepEqual(got, wantChanges) {
+		t.Errorf("changes = %q\nwant %q", got, wantChanges)
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	useTempStateDir(t)
+	status := http.StatusOK
+	cookies := []string{"session"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range cookies {
+			http.SetCookie(w, &http.Cookie{Name: name, Value: "1"})
+		}
+		if status == http.StatusFound {
+			http.Redirect(w, r, "/moved", status)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	target := common.Target{URL: srv.URL + "/", HTTP: common.HTTPConfig{MaxRedirects: -1, AcceptStatus: []int{200, 302, 403}}}
+	check := func() []string {
+		t.Helper()
+		_, info, err := staticHash(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alerts, err := checkResponse(target.URL, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return alerts
+	}
+	check()
+
+	// cookie jar 生效后服务器不再下发，不算变化
+	cookies = nil
+	if alerts := check(); alerts != nil {
+		t.Errorf("cookie 不再下发不应告警: %q", alerts)
+	}
+	cookies = []string{"session"}
+	if alerts := check(); alerts != nil {
+		t.Errorf("已出现过的 cookie 不应告警: %q", alerts)
+	}
+
+	// 不跟随跳转时页面开始跳转只体现在状态码上
+	status, cookies = http.StatusFound, []string{"session", "_track"}
+	alerts := check()
+	want := "<b>" + target.URL + " 响应变化</b>\n状态码变化: 200 → 302\n新出现的 Set-Cookie: _track\n"
+	if len(alerts) != 1 || alerts[0] != want {
+		t.Errorf("alerts = %q\nwant %q", alerts, want)
+	}
+
+	status = http.StatusForbidden
+	if alerts := check(); len(alerts) != 1 || !strings.Contains(alerts[0], "状态码变化: 302 → 403") {
+		t.Errorf("alerts = %q", alerts)
+	}
+}
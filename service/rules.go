@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"store/common"
+	"strings"
+)
+
+// 片段中匹配位置前后保留的字符数
+const snippetContext = 60
+
+// ruleName 规则在告警和状态文件中的名称
+func ruleName(r common.Rule) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Kind == common.RuleCountAbove {
+		return fmt.Sprintf("%s %d: %s", r.Kind, r.Count, r.Value)
+	}
+	return r.Kind + ": " + r.Value
+}
+
+// compileRule 把规则转换成正则，非 regex 规则按字面量匹配
+func compileRule(r common.Rule) (*regexp.Regexp, error) {
+	pattern := r.Value
+	switch r.Kind {
+	case common.RuleRegex:
+	case common.RuleContains, common.RuleNotContains, common.RuleCountAbove:
+		pattern = regexp.QuoteMeta(r.Value)
+	default:
+		return nil, fmt.Errorf("未定义的规则类型: %s", r.Kind)
+	}
+	if r.Value == "" {
+		return nil, fmt.Errorf("规则 %s 缺少 Value", ruleName(r))
+	}
+	if r.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("规则 %s: %w", ruleName(r), err)
+	}
+	return re, nil
+}
+
+// validateRules 检查所有规则能否编译；规则状态按名称保存，名称重复时返回错误
+func validateRules(rules []common.Rule) error {
+	seen := map[string]bool{}
+	for _, r := range rules {
+		if _, err := compileRule(r); err != nil {
+			return err
+		}
+		name := ruleName(r)
+		if seen[name] {
+			return fmt.Errorf("规则名称重复: %s，请用 Name 区分", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// snippet 返回匹配内容及前后若干字符，匹配部分用【】标出
+func snippet(text string, loc []int) string {
+	before := []rune(text[:loc[0]])
+	after := []rune(text[loc[1]:])
+	var sb strings.Builder
+	if len(before) > snippetContext {
+		sb.WriteString("…")
+		before = before[len(before)-snippetContext:]
+	}
+	sb.WriteString(string(before))
+	sb.WriteString("【" + text[loc[0]:loc[1]] + "】")
+	if len(after) > snippetContext {
+		sb.WriteString(string(after[:snippetContext]) + "…")
+	} else {
+		sb.WriteString(string(after))
+	}
+	return sb.String()
+}
+
+// evaluateRules 在提取的文本上计算每条规则，只在规则状态变化时告警：
+// 开始命中时附上匹配片段，不再命中时发送恢复通知。没有记录时视为未命中
+func evaluateRules(target string, rules []common.Rule, text string) ([]string, error) {
+	var old map[string]bool
+	if _, err := loadState("rules", target, &old); err != nil {
+		return nil, fmt.Errorf("%s 读取规则状态失败: %w", target, err)
+	}
+
+	cur := make(map[string]bool, len(rules))
+	var alerts []string
+	for _, r := range rules {
+		re, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", target, err)
+		}
+		matches := re.FindAllStringIndex(text, -1)
+		var firing bool
+		switch r.Kind {
+		case common.RuleNotContains:
+			firing = len(matches) == 0
+		case common.RuleCountAbove:
+			firing = len(matches) > r.Count
+		default:
+			firing = len(matches) > 0
+		}
+		name := ruleName(r)
+		cur[name] = firing
+		if firing == old[name] {
+			continue
+		}
+
+		title := fmt.Sprintf("%s 规则触发: %s", target, name)
+		if !firing {
+			title = fmt.Sprintf("%s 规则恢复: %s", target, name)
+		}
+		lines := []string{fmt.Sprintf("匹配 %d 次", len(matches))}
+		for i, loc := range matches {
+			if i == 3 {
+				lines = append(lines, fmt.Sprintf("… 其余 %d 处省略", len(matches)-i))
+				break
+			}
+			lines = append(lines, snippet(text, loc))
+		}
+		alerts = append(alerts, formatChanges(title, lines))
+	}
+	if err := saveState("rules", target, cur); err != nil {
+		return nil, fmt.Errorf("%s 保存规则状态失败: %w", target, err)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"store/common"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateRules(t *testing.T) {
+	useTempStateDir(t)
+	target := "https://store.gavinnewsom.com/"
+	rules := []common.Rule{
+		{Kind: common.RuleContains, Value: "sold out", IgnoreCase: true},
+		{Kind: common.RuleNotContains, Value: "Add to Cart", Name: "购物车按钮"},
+		{Kind: common.RuleCountAbove, Value: "$", Count: 2},
+	}
+
+	text := "Patriot Hat $30 Add to Cart"
+	alerts, err := evaluateRules(target, rules, text)
+	if err != nil || len(alerts) != 0 {
+		t.Fatalf("未命中时不应告警: %q err=%v", alerts, err)
+	}
+
+	text = strings.Repeat("x", 100) + " Patriot Hat SOLD OUT $30 $25 $20"
+	alerts, err = evaluateRules(target, rules, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 3 {
+		t.Fatalf("alerts = %q", alerts)
+	}
+	if !strings.Contains(alerts[0], "规则触发: contains: sold out") ||
+		!strings.Contains(alerts[0], "…"+strings.Repeat("x", 47)+" Patriot Hat 【SOLD OUT】 $30 $25 $20") {
+		t.Errorf("contains 告警: %q", alerts[0])
+	}
+	if !strings.Contains(alerts[1], "规则触发: 购物车按钮") || !strings.Contains(alerts[2], "匹配 3 次") {
+		t.Errorf("alerts = %q", alerts)
+	}
+
+	// 状态不变时不重复告警
+	if alerts, _ = evaluateRules(target, rules, text); len(alerts) != 0 {
+		t.Errorf("状态未变化: %q", alerts)
+	}
+
+	alerts, err = evaluateRules(target, rules, "Patriot Hat $30 Add to Cart")
+	if err != nil || len(alerts) != 3 || !strings.Contains(alerts[0], "规则恢复") {
+		t.Errorf("恢复告警: %q err=%v", alerts, err)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	ok := []common.Rule{
+		{Kind: common.RuleContains, Value: "SOLD OUT"},
+		{Kind: common.RuleContains, Value: "SOLD OUT", IgnoreCase: true, Name: "售罄(忽略大小写)"},
+	}
+	if err := validateRules(ok); err != nil {
+		t.Errorf("名称不同的规则应通过: %v", err)
+	}
+	dup := []common.Rule{
+		{Kind: common.RuleContains, Value: "SOLD OUT"},
+		{Kind: common.RuleContains, Value: "SOLD OUT", IgnoreCase: true},
+	}
+	if err := validateRules(dup); err == nil {
+		t.Error("名称重复的规则会共用状态，应返回错误")
+	}
+	if err := validateRules([]common.Rule{{Kind: common.RuleRegex, Value: "("}}); err == nil {
+		t.Error("无效正则应返回错误")
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"store/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField 一个 cron 字段允许的取值
+type cronField struct {
+	allowed map[int]bool
+	any     bool // 字段为 *（或 */1），用于日和周的组合判断
+}
+
+func (f cronField) match(v int) bool {
+	return f.allowed[v]
+}
+
+// cronExpr 标准 5 段 cron 表达式：分 时 日 月 周
+type cronExpr struct {
+	minute, hour, dom, month, dow cronField
+}
+
+// parseCron 支持 *、数字、a-b 区间、/n 步长和逗号列表，周日可写 0 或 7
+func parseCron(spec string) (*cronExpr, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式 %q 需要 5 个字段", spec)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var parsed [5]cronField
+	for i, f := range fields {
+		cf, err := parseCronField(f, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron 表达式 %q: %w", spec, err)
+		}
+		parsed[i] = cf
+	}
+	if parsed[4].allowed[7] {
+		parsed[4].allowed[0] = true
+	}
+	return &cronExpr{minute: parsed[0], hour: parsed[1], dom: parsed[2], month: parsed[3], dow: parsed[4]}, nil
+}
+
+func parseCronField(field string, lo, hi int) (cronField, error) {
+	cf := cronField{allowed: map[int]bool{}}
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return cf, fmt.Errorf("无效步长 %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		from, to := lo, hi
+		switch {
+		case rng == "*":
+			if step == 1 {
+				cf.any = true
+			}
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			var err1, err2 error
+			from, err1 = strconv.Atoi(a)
+			to, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return cf, fmt.Errorf("无效区间 %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return cf, fmt.Errorf("无效取值 %q", part)
+			}
+			from, to = n, n
+			if step > 1 {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return cf, fmt.Errorf("%q 超出范围 %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			cf.allowed[v] = true
+		}
+	}
+	return cf, nil
+}
+
+// dayMatch 日和周都有限制时满足其一即可，与标准 cron 一致
+func (c *cronExpr) dayMatch(t time.Time) bool {
+	dom, dow := c.dom.match(t.Day()), c.dow.match(int(t.Weekday()))
+	if c.dom.any || c.dow.any {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next 返回严格晚于 t 的下一个匹配时间，5 年内找不到时返回零值
+func (c *cronExpr) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.month.match(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatch(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hour.match(t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute.match(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// window 一天中的时间段，单位为当天零点起的分钟数
+type window struct {
+	from, to int
+}
+
+func parseClock(s string) (int, error) {
+	tm, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("无效时间 %q，应为 HH:MM", s)
+	}
+	return tm.Hour()*60 + tm.Minute(), nil
+}
+
+// end 返回 t 所在时间段的结束时刻，t 不在时间段内时返回 false
+func (w window) end(t time.Time) (time.Time, bool) {
+	m := t.Hour()*60 + t.Minute()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch {
+	case w.from <= w.to && m >= w.from && m < w.to:
+		return midnight.Add(time.Duration(w.to) * time.Minute), true
+	case w.from > w.to && m >= w.from: // 跨午夜，当前在前半段
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(w.to) * time.Minute), true
+	case w.from > w.to && m < w.to: // 跨午夜，当前在后半段
+		return midnight.Add(time.Duration(w.to) * time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// 未配置 MaxBackoff 时失败退避的上限为 Max 的倍数
+const defaultBackoffFactor = 4
+
+// schedule 一个目标的调度规则，自适应模式下还记录当前间隔和连续失败次数
+type schedule struct {
+	interval time.Duration
+	jitter   time.Duration
+	crons    []*cronExpr
+	blackout []window
+
+	adaptive *common.Adaptive
+	current  time.Duration
+	failures int
+}
+
+func newSchedule(t common.Target) (*schedule, error) {
+	s := &schedule{interval: t.Interval, jitter: t.Jitter}
+	for _, spec := range t.Cron {
+		c, err := parseCron(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.crons = append(s.crons, c)
+	}
+	for _, w := range t.Blackout {
+		from, err := parseClock(w.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseClock(w.To)
+		if err != nil {
+			return nil, err
+		}
+		s.blackout = append(s.blackout, window{from: from, to: to})
+	}
+	if a := t.Adaptive; a != nil {
+		if len(s.crons) > 0 {
+			return nil, fmt.Errorf("%s 不能同时配置 Adaptive 和 Cron", t.URL)
+		}
+		if a.Min <= 0 || a.Max < a.Min || a.Growth < 1 {
+			return nil, fmt.Errorf("%s Adaptive 配置无效: 需要 0 < Min <= Max 且 Growth >= 1", t.URL)
+		}
+		cfg := *a
+		if cfg.MaxBackoff == 0 {
+			cfg.MaxBackoff = defaultBackoffFactor * cfg.Max
+		} else if cfg.MaxBackoff < cfg.Max {
+			return nil, fmt.Errorf("%s Adaptive 配置无效: MaxBackoff 不能小于 Max", t.URL)
+		}
+		s.adaptive, s.current = &cfg, cfg.Min
+	}
+	if len(s.crons) == 0 && s.interval <= 0 && s.adaptive == nil {
+		return nil, fmt.Errorf("%s 未配置 Interval、Cron 或 Adaptive", t.URL)
+	}
+	return s, nil
+}
+
+// observe 根据本次检查结果调整自适应间隔：变化后回到最短间隔，未变化时逐步放宽，失败时累计退避次数
+func (s *schedule) observe(changed, failed bool) {
+	if s.adaptive == nil {
+		return
+	}
+	if failed {
+		s.failures++
+		return
+	}
+	s.failures = 0
+	if changed {
+		s.current = s.adaptive.Min
+		return
+	}
+	s.current = min(time.Duration(float64(s.current)*s.adaptive.Growth), s.adaptive.Max)
+}
+
+// adaptiveInterval 当前自适应间隔，连续失败 n 次时为 间隔*2^n，不超过 MaxBackoff
+func (s *schedule) adaptiveInterval() time.Duration {
+	d := s.current
+	if s.failures == 0 {
+		return d
+	}
+	limit := max(s.adaptive.MaxBackoff, s.current)
+	for i := 0; i < s.failures && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// first 启动后的第一次检查时间，在 [0, Jitter) 内随机错开
+func (s *schedule) first(now time.Time) time.Time {
+	return s.avoidBlackout(now.Add(s.randJitter()))
+}
+
+// next 上一次检查结束后的下一次检查时间
+func (s *schedule) next(now time.Time) time.Time {
+	var t time.Time
+	if len(s.crons) > 0 {
+		for _, c := range s.crons {
+			n := c.next(now)
+			if !n.IsZero() && (t.IsZero() || n.Before(t)) {
+				t = n
+			}
+		}
+		if t.IsZero() {
+			// cron 永远不会再匹配时退化为固定间隔
+			t = now.Add(max(s.interval, time.Hour))
+		}
+	} else if s.adaptive != nil {
+		t = now.Add(s.adaptiveInterval())
+	} else {
+		t = now.Add(s.interval)
+	}
+	return s.avoidBlackout(t.Add(s.randJitter()))
+}
+
+// avoidBlackout 落在禁止时间段内时顺延到时间段结束，多个时间段相连时依次顺延
+func (s *schedule) avoidBlackout(t time.Time) time.Time {
+	for i := 0; i <= len(s.blackout); i++ {
+		moved := false
+		for _, w := range s.blackout {
+			if end, ok := w.end(t); ok {
+				t, moved = end.Add(s.randJitter()), true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return t
+}
+
+func (s *schedule) randJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
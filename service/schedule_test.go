@@ -0,0 +1,139 @@
+package service
+
+import (
+	"store/common"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	cases := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"*/15 * * * *", "2025-10-01 10:07", "2025-10-01 10:15"},
+		{"0 9-18 * * 1-5", "2025-10-03 18:30", "2025-10-06 09:00"}, // 周五晚 -> 周一早
+		{"30 2 1 * *", "2025-12-15 00:00", "2026-01-01 02:30"},
+		{"0 0 * * 7", "2025-10-01 00:00", "2025-10-05 00:00"}, // 7 等同周日
+		{"5 */6 * * *", "2025-10-01 06:05", "2025-10-01 12:05"},
+	}
+	for _, c := range cases {
+		expr, err := parseCron(c.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", c.spec, err)
+		}
+		from, _ := time.ParseInLocation("2006-01-02 15:04", c.from, time.Local)
+		got := expr.next(from).Format("2006-01-02 15:04")
+		if got != c.want {
+			t.Errorf("%q next(%s) = %s, 期望 %s", c.spec, c.from, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("parseCron(%q) 应返回错误", bad)
+		}
+	}
+}
+
+func TestScheduleBlackoutAndCron(t *testing.T) {
+	sched, err := newSchedule(common.Target{
+		URL:      "https://example.com/",
+		Cron:     []string{"*/5 9-17 * * *", "0 * * * *"},
+		Blackout: []common.Window{{From: "23:00", To: "06:00"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		return tm
+	}
+
+	// 白天取更密集的规则
+	if got := sched.next(at("2025-10-01 10:01")); !got.Equal(at("2025-10-01 10:05")) {
+		t.Errorf("白天下次检查 = %s", got)
+	}
+	// 夜间只剩整点规则
+	if got := sched.next(at("2025-10-01 20:01")); !got.Equal(at("2025-10-01 21:00")) {
+		t.Errorf("夜间下次检查 = %s", got)
+	}
+	// 落在跨午夜的禁止时段内，顺延到 06:00
+	if got := sched.next(at("2025-10-01 22:30")); !got.Equal(at("2025-10-02 06:00")) {
+		t.Errorf("禁止时段下次检查 = %s", got)
+	}
+
+	if _, err := newSchedule(common.Target{URL: "https://example.com/"}); err == nil {
+		t.Error("未配置间隔和 cron 时应返回错误")
+	}
+}
+
+func TestScheduleAdaptive(t *testing.T) {
+	sched, err := newSchedule(common.Target{
+		URL: "https://example.com/",
+		Adaptive: &common.Adaptive{
+			Min: time.Minute, Max: 4 * time.Minute, Growth: 2, MaxBackoff: 10 * time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.Local)
+	expect := func(want time.Duration) {
+		t.Helper()
+		if got := sched.next(now).Sub(now); got != want {
+			t.Errorf("间隔 = %s, 期望 %s", got, want)
+		}
+	}
+
+	expect(time.Minute)
+	// 页面不变时逐步放宽到上限
+	sched.observe(false, false)
+	expect(2 * time.Minute)
+	sched.observe(false, false)
+	sched.observe(false, false)
+	expect(4 * time.Minute)
+	// 连续失败指数退避，不超过 MaxBackoff
+	sched.observe(false, true)
+	expect(8 * time.Minute)
+	sched.observe(false, true)
+	expect(10 * time.Minute)
+	// 恢复后检测到变化，回到最短间隔
+	sched.observe(true, false)
+	expect(time.Minute)
+
+	if _, err := newSchedule(common.Target{
+		URL:      "https://example.com/",
+		Cron:     []string{"* * * * *"},
+		Adaptive: &common.Adaptive{Min: time.Minute, Max: time.Minute, Growth: 1},
+	}); err == nil {
+		t.Error("Adaptive 和 Cron 同时配置时应返回错误")
+	}
+
+	if _, err := newSchedule(common.Target{
+		URL:      "https://example.com/",
+		Adaptive: &common.Adaptive{Min: time.Minute, Max: 4 * time.Minute, Growth: 2, MaxBackoff: 2 * time.Minute},
+	}); err == nil {
+		t.Error("MaxBackoff 小于 Max 时应返回错误")
+	}
+}
+
+func TestScheduleAdaptiveDefaultBackoff(t *testing.T) {
+	sched, err := newSchedule(common.Target{
+		URL:      "https://example.com/",
+		Adaptive: &common.Adaptive{Min: time.Minute, Max: 2 * time.Minute, Growth: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.Local)
+	// 未配置 MaxBackoff 时失败仍然退避，上限为 4 倍 Max
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 8 * time.Minute}
+	for i, w := range want {
+		sched.observe(false, true)
+		if got := sched.next(now).Sub(now); got != w {
+			t.Errorf("第 %d 次失败后间隔 = %s, 期望 %s", i+1, got, w)
+		}
+	}
+}
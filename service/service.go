@@ -1,9 +1,9 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/playwright-community/playwright-go"
 	"log"
 	"os"
 	"os/signal"
@@ -17,12 +17,15 @@ import (
 var (
 	mu    sync.Mutex
 	Store HashStore
+	// 所有 monitor 协程，退出时等待它们结束后再保存
+	monitors sync.WaitGroup
 )
 
 // HashStore 用来存储 URL 和 hash
 type HashStore map[string]string
 
-func Hash(browser playwright.Browser) {
+// Hash 为每个监控目标启动监控协程，ctx 取消后协程在当前检查结束时退出
+func Hash(ctx context.Context, pool *utils.BrowserPool) {
 	// 读取tg频道配置
 	token := os.Getenv("TELEGRAM_TOKEN")
 	chatID := os.Getenv("TELEGRAM_CHATID")
@@ -45,31 +48,69 @@ func Hash(browser playwright.Browser) {
 	//if err != nil {
 	//	log.Fatal(err)
 	//}
-	urls := []string{common.Url1, common.Url2, common.Url3, common.Url4}
-	for _, u := range urls {
-		go monitor(bot, browser, u, 20*time.Second)
+	seen := map[string]bool{}
+	for _, t := range common.Targets {
+		if !knownMode(t.Mode) {
+			log.Fatalf("%s 未定义的抓取方式: %s", t.URL, t.Mode)
+		}
+		key := targetKey(t)
+		if seen[key] {
+			log.Fatalf("%s 重复配置: 同一 URL 的 static 和 dynamic 目标只能有一个，其他方式每种只能有一个", t.URL)
+		}
+		seen[key] = true
+		sched, err := newSchedule(t)
+		if err != nil {
+			log.Fatalf("%s 调度配置错误: %v", t.URL, err)
+		}
+		if t.Crawl != nil {
+			if _, _, err := crawlPatterns(t.Crawl); err != nil {
+				log.Fatalf("%s 抓取配置错误: %v", t.URL, err)
+			}
+			if m := t.Crawl.Mode; m != "" && (!knownMode(m) || m == common.ModeCrawl) {
+				log.Fatalf("%s 发现页面的抓取方式无效: %s", t.URL, m)
+			}
+		}
+		if t.Meta && t.Mode != common.ModeStatic && t.Mode != common.ModeDynamic {
+			log.Fatalf("%s Meta 只支持 static 和 dynamic 模式", t.URL)
+		}
+		if t.Links != nil {
+			if _, err := compilePatterns(t.Links.Exclude); err != nil {
+				log.Fatalf("%s 链接检查配置错误: %v", t.URL, err)
+			}
+		}
+		if err := validateRules(t.Rules); err != nil {
+			log.Fatalf("%s 规则配置错误: %v", t.URL, err)
+		}
+		if err := validateExtractors(t.Mode, t.Extract); err != nil {
+			log.Fatalf("%s 数值提取配置错误: %v", t.URL, err)
+		}
+		monitors.Add(1)
+		go func() {
+			defer monitors.Done()
+			monitor(ctx, bot, pool, t, sched)
+		}()
+	}
+	if addr := os.Getenv("STATUS_ADDR"); addr != "" {
+		StartStatusServer(ctx, addr)
 	}
 }
 
-func monitor(bot *utils.TelegramBot, browser playwright.Browser, url string, interval time.Duration) {
-	lastHash := Store[url]
+func monitor(ctx context.Context, bot *utils.TelegramBot, pool *utils.BrowserPool, t common.Target, sched *schedule) {
+	url, key := t.URL, targetKey(t)
+	mu.Lock()
+	lastHash := Store[key]
+	mu.Unlock()
+	updateStatus(key, func(s *TargetStatus) { s.URL, s.Mode = t.URL, t.Mode })
+
+	// 启动时随机错开，避免所有目标同一时刻请求
+	if !waitUntil(ctx, key, sched.first(time.Now())) {
+		return
+	}
 
 	for {
 
-		var (
-			text, hash string
-			err        error
-		)
-
-		switch url {
-		case common.Url1, common.Url2, common.Url3:
-			text, hash, err = staticHash(url)
-		case common.Url4:
-			text, hash, err = dynamicHash(browser, url)
-		default:
-			log.Printf("未定义的监控 URL: %s", url)
-			return
-		}
+		res, err := fetch(ctx, pool, t)
+		text, hash := res.text, res.hash
 
 		// 第一次启动时，写入一次 baseline
 		//if lastHash == "" {
@@ -83,64 +124,235 @@ func monitor(bot *utils.TelegramBot, browser playwright.Browser, url string, int
 		//	continue
 		//}
 
+		// 与内容无关的告警单独推送，检查失败时也可能有（如页面开始返回错误状态码时的响应变化）
+		for _, alert := range res.alerts {
+			if err := bot.SendMessage(ctx, alert); err != nil {
+				log.Println(err)
+			}
+		}
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			log.Println(err)
+			updateStatus(key, func(s *TargetStatus) {
+				s.LastCheck, s.LastError = time.Now(), err.Error()
+			})
+			sched.observe(false, true)
 		} else {
+			if t.Mode == common.ModeCrawl {
+				watchPages(ctx, bot, pool, t, res.pages)
+			}
 			if lastHash != "" && lastHash != hash {
 				if err := utils.AppendUpdateLog(url, text); err != nil {
 					log.Printf("写入日志失败: %v", err)
 				} else {
 					log.Printf("变更内容已写入 update.txt")
 				}
-				// 根据 URL 选择更新方法
-				switch url {
-				case common.Url3:
-					dynamicUpdate(bot, browser, url)
-				default:
-					staticUpdate(bot, url)
+				// 根据配置选择更新方法
+				if t.Visual {
+					dynamicUpdate(ctx, bot, pool, t)
+				} else {
+					staticUpdate(ctx, bot, url, res.changes)
+				}
+				for _, d := range res.documents {
+					if err := bot.SendDocument(ctx, d.path, d.caption); err != nil {
+						log.Println(err)
+					}
+				}
+				// 退出时通知可能没有发完，不更新 hash，下次启动重新检测
+				if ctx.Err() != nil {
+					return
 				}
 			}
 			// 更新内存 store，不写盘
 			mu.Lock()
-			Store[url] = hash
+			Store[key] = hash
 			mu.Unlock()
+			changed := lastHash != "" && lastHash != hash
 			lastHash = hash
+			updateStatus(key, func(s *TargetStatus) {
+				s.LastCheck, s.LastError = time.Now(), ""
+				if changed {
+					s.LastChange = s.LastCheck
+				}
+			})
+			sched.observe(changed, false)
+		}
+		if !waitUntil(ctx, key, sched.next(time.Now())) {
+			return
+		}
+	}
+}
+
+// waitUntil 记录下次运行时间并等待到该时刻，ctx 取消时返回 false
+func waitUntil(ctx context.Context, key string, next time.Time) bool {
+	updateStatus(key, func(s *TargetStatus) { s.NextRun = next })
+	log.Printf("%s 下次检查: %s", key, next.Format("2006-01-02 15:04:05"))
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// checkResult 一次检查的结果
+type checkResult struct {
+	text   string   // 页面文本，变化时写入 update.txt
+	hash   string   // 内容哈希，用于判断是否变化
+	html   string   // static/dynamic 抓取的 HTML，供数值提取使用
+	page   string   // 整页 HTML（含 <head>），开启 Meta 时供元数据检查使用，304 时为空
+	alerts []string // 与内容哈希无关、需要单独推送的告警（HTML 格式）
+	// changes 与上次检查相比的具体变化，内容哈希变化时附在更新通知中
+	changes []string
+	// pages crawl 模式发现的页面
+	pages []string
+	// documents 内容哈希变化时随更新通知发送的文件
+	documents []document
+}
+
+// targetKey 目标在 Store、运行状态、HTTP Client 缓存和规则、时间序列记录中的键。
+// 同一个 URL 可以用不同方式各监控一次；static/dynamic 直接使用 URL，兼容已保存的 hash 文件
+func targetKey(t common.Target) string {
+	switch t.Mode {
+	case common.ModeStatic, common.ModeDynamic, "":
+		return t.URL
+	}
+	return t.URL + " (" + t.Mode + ")"
+}
+
+func knownMode(mode string) bool {
+	switch mode {
+	case common.ModeStatic, common.ModeDynamic, common.ModeJSON, common.ModeFeed, common.ModeSitemap,
+		common.ModeCrawl, common.ModeLinks, common.ModeAsset:
+		return true
+	}
+	return false
+}
+
+// fetch 按目标的抓取方式执行一次检查，再附上元数据、关键词规则、数值提取和基础设施检查的告警
+func fetch(ctx context.Context, pool *utils.BrowserPool, t common.Target) (checkResult, error) {
+	res, err := fetchContent(ctx, pool, t)
+	if t.Infra != nil {
+		// 基础设施检查与内容检查互不影响，抓取失败时（比如证书过期、DNS 异常）也照常检查
+		alerts, err := checkInfra(ctx, t)
+		if err != nil {
+			log.Println(err)
 		}
-		time.Sleep(interval)
+		res.alerts = append(res.alerts, alerts...)
+	}
+	if err != nil {
+		return res, err
 	}
+	if t.Meta && res.page != "" {
+		alerts, err := checkMeta(t.URL, res.page)
+		if err != nil {
+			log.Println(err)
+		}
+		res.alerts = append(res.alerts, alerts...)
+	}
+	if len(t.Rules) > 0 {
+		alerts, err := evaluateRules(targetKey(t), t.Rules, res.text)
+		if err != nil {
+			log.Println(err)
+		}
+		res.alerts = append(res.alerts, alerts...)
+	}
+	if len(t.Extract) > 0 {
+		alerts, err := recordMetrics(targetKey(t), t.Extract, res)
+		if err != nil {
+			log.Println(err)
+		}
+		res.alerts = append(res.alerts, alerts...)
+	}
+	return res, nil
+}
+
+func fetchContent(ctx context.Context, pool *utils.BrowserPool, t common.Target) (checkResult, error) {
+	switch t.Mode {
+	case common.ModeStatic:
+		res, info, err := staticHash(ctx, t)
+		if t.Response && info != nil {
+			alerts, err := checkResponse(t.URL, info)
+			if err != nil {
+				log.Println(err)
+			}
+			res.alerts = append(res.alerts, alerts...)
+		}
+		return res, err
+	case common.ModeDynamic:
+		res, snap, err := dynamicHash(ctx, pool, t)
+		if err != nil {
+			return checkResult{}, err
+		}
+		if snap != nil {
+			alerts, err := checkNetwork(t.URL, snap)
+			if err != nil {
+				log.Println(err)
+			}
+			res.alerts = append(res.alerts, alerts...)
+		}
+		return res, nil
+	case common.ModeJSON:
+		return jsonHash(ctx, t)
+	case common.ModeFeed, common.ModeSitemap:
+		return feedHash(ctx, t)
+	case common.ModeCrawl:
+		if t.Crawl == nil {
+			return checkResult{}, fmt.Errorf("%s crawl 模式缺少 Crawl 配置", t.URL)
+		}
+		return crawlHash(ctx, t)
+	case common.ModeLinks:
+		return linksHash(ctx, t)
+	case common.ModeAsset:
+		return assetHash(ctx, t)
+	}
+	return checkResult{}, fmt.Errorf("%s 未定义的抓取方式: %s", t.URL, t.Mode)
 }
 
-func staticUpdate(bot *utils.TelegramBot, url string) {
+func staticUpdate(ctx context.Context, bot *utils.TelegramBot, url string, changes []string) {
 	msg := fmt.Sprintf("%s 网站更新", url)
-	err := bot.SendMessage(msg)
+	if len(changes) > 0 {
+		msg = formatChanges(msg, changes)
+	}
+	err := bot.SendMessage(ctx, msg)
 	if err != nil {
 		log.Println(err)
 	}
 }
 
-func dynamicUpdate(bot *utils.TelegramBot, browser playwright.Browser, url string) {
+// dynamicUpdate 文本变化后做截图对比，更新通知随差异图发送，视觉变化低于 PngAlertPercent 时不通知；
+// 截图对比最终失败时仍然发送文本更新通知
+func dynamicUpdate(ctx context.Context, bot *utils.TelegramBot, pool *utils.BrowserPool, t common.Target) {
+	url := t.URL
 	msg := fmt.Sprintf("%s 网站更新", url)
-	err := bot.SendMessage(msg)
+	err := bot.SendMessage(ctx, msg)
 	if err != nil {
 		log.Println(err)
 	}
 	const maxRetries = 3
 	for i := 1; i <= maxRetries; i++ {
-		err = utils.SaveAndDiff(bot, browser, url)
-		if err == nil {
-			// 成功就跳出
+		err = utils.SaveAndDiff(ctx, bot, pool, t)
+		if err == nil || ctx.Err() != nil {
+			// 成功或正在退出就跳出
 			break
 		}
 		log.Printf("SaveAndDiff 失败 (第 %d 次): %v", i, err)
 		if i < maxRetries {
-			time.Sleep(time.Second * 1) // 等待 2 秒再试，可调
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second * 1): // 等待 2 秒再试，可调
+			}
 		}
 	}
 
 	if err != nil {
 		log.Printf("SaveAndDiff 最终失败: %v", err)
 	}
-	//err = utils.SaveAndDiff(bot, browser, url)
+	//err = utils.SaveAndDiff(ctx, bot, pool, t)
 }
 
 // LoadHashStore 读取持久化的 hash
@@ -183,14 +395,30 @@ func saveHashStore() error {
 	return enc.Encode(Store)
 }
 
-// SetupGracefulShutdown 监听信号并优雅关闭
-func SetupGracefulShutdown() {
+// SetupGracefulShutdown 监听信号并优雅关闭：取消进行中的检查，在期限内等待监控协程退出后再保存
+func SetupGracefulShutdown(cancel context.CancelFunc) {
 	// 统一处理退出信号
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
 
-	log.Println("收到退出信号，保存 hash_store.json ...")
+	log.Println("收到退出信号，停止监控任务 ...")
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		monitors.Wait()
+		close(done)
+	}()
+	timeout := common.EnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	select {
+	case <-done:
+		log.Println("监控任务已全部退出")
+	case <-time.After(timeout):
+		log.Printf("等待监控任务退出超时 (%s)，直接保存", timeout)
+	}
+
+	log.Println("保存 hash_store.json ...")
 	if err := saveHashStore(); err != nil {
 		log.Printf("保存失败: %v", err)
 	}
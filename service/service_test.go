@@ -0,0 +1,38 @@
+package service
+
+import (
+	"store/common"
+	"testing"
+)
+
+func TestTargetKey(t *testing.T) {
+	url := "https://example.com/"
+	static := targetKey(common.Target{URL: url, Mode: common.ModeStatic})
+	dynamic := targetKey(common.Target{URL: url, Mode: common.ModeDynamic})
+	links := targetKey(common.Target{URL: url, Mode: common.ModeLinks})
+	feed := targetKey(common.Target{URL: url, Mode: common.ModeFeed})
+
+	// static/dynamic 沿用 URL，兼容已保存的 hash
+	if static != url || dynamic != url {
+		t.Errorf("static/dynamic 的键 = %q %q, 期望 %q", static, dynamic, url)
+	}
+	if links == url || links == feed {
+		t.Errorf("不同抓取方式的键不应相同: %q %q", links, feed)
+	}
+}
+
+// useTempStateDir 把 common.StateDir 指向临时目录，测试结束后恢复
+func useTempStateDir(t *testing.T) {
+	t.Helper()
+	old := common.StateDir
+	common.StateDir = t.TempDir()
+	t.Cleanup(func() { common.StateDir = old })
+}
+
+// useTempAssetDir 把 common.AssetDir 指向临时目录，测试结束后恢复
+func useTempAssetDir(t *testing.T) {
+	t.Helper()
+	old := common.AssetDir
+	common.AssetDir = t.TempDir()
+	t.Cleanup(func() { common.AssetDir = old })
+}
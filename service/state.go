@@ -0,0 +1,65 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"store/common"
+)
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// statePath 返回某类检查状态的文件路径：state/<kind>/<域名_路径>_<短哈希>.json
+func statePath(kind, target string) string {
+	return filepath.Join(common.StateDir, kind, safeName(target)+".json")
+}
+
+// safeName 把 URL 转换成可读且不冲突的文件名：<域名_路径>_<短哈希>
+func safeName(target string) string {
+	name := target
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = unsafeChars.ReplaceAllString(name, "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	sum := sha1.Sum([]byte(target))
+	return name + "_" + hex.EncodeToString(sum[:4])
+}
+
+// loadState 读取上一次保存的状态，文件不存在时返回 false
+func loadState(kind, target string, v any) (bool, error) {
+	data, err := os.ReadFile(statePath(kind, target))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// saveState 保存状态，先写临时文件再改名，避免退出时写到一半
+func saveState(kind, target string, v any) error {
+	path := statePath(kind, target)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -1,39 +1,103 @@
 package service
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"log"
 	"net/http"
+	"store/common"
 	"strings"
+	"sync"
+	"time"
 )
 
-func staticHash(url string) (string, string, error) {
+// 即使服务器一直返回 304，也至少每隔这么久做一次完整抓取，防止服务器校验头不可靠
+const fullFetchInterval = time.Hour
+
+// validator 条件请求缓存：记录每个 URL 的校验头和上次完整抓取的结果
+type validator struct {
+	etag         string
+	lastModified string
+	text         string
+	hash         string
+	html         string    // 只在配置了 Extract 时缓存
+	fullAt       time.Time // 上次完整抓取时间
+	notModified  int       // 上次完整抓取后收到 304 的次数
+}
+
+var (
+	validatorMu sync.Mutex
+	validators  = map[string]*validator{}
+)
+
+// staticHash 抓取页面文本并计算哈希；info 为本次响应的状态、跳转链和响应头，
+// 状态码不被接受时也会返回，收到 304 时为 nil（304 的响应头不完整）
+func staticHash(ctx context.Context, t common.Target) (res checkResult, info *ResponseInfo, err error) {
+	url := t.URL
+	client, err := httpClient(t)
+	if err != nil {
+		return checkResult{}, nil, err
+	}
+
 	// 创建请求
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", "", fmt.Errorf("%s 请求创建失败:%w", url, err)
+		return checkResult{}, nil, fmt.Errorf("%s 请求创建失败:%w", url, err)
 	}
 
 	// 可选：添加请求头，伪装成浏览器
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/139.0.0.0 Safari/537.36")
+	req.Header.Set("User-Agent", common.UserAgent)
+	applyHTTPConfig(req, t.HTTP)
+
+	// 有校验头且未到完整抓取时间时发送条件请求
+	validatorMu.Lock()
+	v, ok := validators[url]
+	if !ok {
+		v = &validator{}
+		validators[url] = v
+	}
+	prev := *v
+	validatorMu.Unlock()
+	conditional := prev.hash != "" && (prev.etag != "" || prev.lastModified != "") &&
+		time.Since(prev.fullAt) < fullFetchInterval
+	if conditional {
+		if prev.etag != "" {
+			req.Header.Set("If-None-Match", prev.etag)
+		}
+		if prev.lastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.lastModified)
+		}
+	}
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("%s 请求发送失败:%w", url, err)
+		return checkResult{}, nil, fmt.Errorf("%s 请求发送失败:%w", url, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", "", fmt.Errorf("%s 响应错误: %d", url, resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		validatorMu.Lock()
+		v.notModified++
+		validatorMu.Unlock()
+		log.Printf("%s 未修改 (304)", url)
+		return checkResult{text: prev.text, hash: prev.hash, html: prev.html}, nil, nil
+	}
+	info = responseInfo(resp)
+	if !statusAccepted(t.HTTP, resp.StatusCode) {
+		return checkResult{}, info, fmt.Errorf("%s 响应错误: %d", url, resp.StatusCode)
 	}
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return "", "", fmt.Errorf("%s 解析 HTML 失败:%w", url, err)
+		return checkResult{}, info, fmt.Errorf("%s 解析 HTML 失败:%w", url, err)
 	}
 
+	html, err := doc.Html()
+	if err != nil {
+		return checkResult{}, info, fmt.Errorf("%s 解析 HTML 失败:%w", url, err)
+	}
 	doc.Find("script, style").Remove()
 	bodyText := doc.Find("body").Text()
 	bodyText = strings.Join(strings.Fields(bodyText), " ")
@@ -41,6 +105,26 @@ func staticHash(url string) (string, string, error) {
 
 	// ---- SHA256 ----
 	sha256Hash := sha256.Sum256([]byte(bodyText))
-	fmt.Println("SHA256:", hex.EncodeToString(sha256Hash[:]))
-	return bodyText, hex.EncodeToString(sha256Hash[:]), nil
+	hash := hex.EncodeToString(sha256Hash[:])
+	fmt.Println("SHA256:", hash)
+
+	if prev.notModified > 0 && prev.hash != hash {
+		log.Printf("%s 内容已变化但服务器此前返回了 %d 次 304，校验头可能不可靠", url, prev.notModified)
+	}
+	// HTML 只在数值提取需要时缓存，供 304 时复用
+	cached := ""
+	if len(t.Extract) > 0 {
+		cached = html
+	}
+	validatorMu.Lock()
+	*v = validator{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		text:         bodyText,
+		hash:         hash,
+		html:         cached,
+		fullAt:       time.Now(),
+	}
+	validatorMu.Unlock()
+	return checkResult{text: bodyText, hash: hash, html: html, page: html}, info, nil
 }
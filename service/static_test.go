@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"store/common"
+	"testing"
+	"time"
+)
+
+func TestStaticHashConditional(t *testing.T) {
+	var full, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("<html><body><p>hello</p></body></html>"))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	// 配置了 Extract 时 304 也要返回 HTML
+	target := common.Target{URL: srv.URL, Extract: []common.Extractor{{Name: "n"}}}
+	first, _, err := staticHash(ctx, target)
+	if err != nil || first.text != "hello" {
+		t.Fatalf("首次抓取: text=%q err=%v", first.text, err)
+	}
+	second, _, err := staticHash(ctx, target)
+	if err != nil || second.text != first.text || second.hash != first.hash || second.html != first.html {
+		t.Fatalf("304 应返回上次结果: text=%q err=%v", second.text, err)
+	}
+	if full != 1 || notModified != 1 {
+		t.Fatalf("完整抓取 %d 次, 304 %d 次", full, notModified)
+	}
+
+	// 超过完整抓取间隔后不再发送条件请求
+	validatorMu.Lock()
+	validators[srv.URL].fullAt = time.Now().Add(-fullFetchInterval)
+	validatorMu.Unlock()
+	if _, _, err = staticHash(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	if full != 2 {
+		t.Errorf("应强制完整抓取, 实际完整抓取 %d 次", full)
+	}
+
+	// 没有 Extract 时不缓存 HTML
+	target.Extract = nil
+	validatorMu.Lock()
+	validators[srv.URL].fullAt = time.Now().Add(-fullFetchInterval)
+	validatorMu.Unlock()
+	if _, _, err = staticHash(ctx, target); err != nil {
+		t.Fatal(err)
+	}
+	validatorMu.Lock()
+	cached := validators[srv.URL].html
+	validatorMu.Unlock()
+	if cached != "" {
+		t.Error("未配置 Extract 时不应缓存 HTML")
+	}
+}
+
+func TestStaticHashHTTPConfig(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Test") != "1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("<body>ok</body>"))
+	}))
+	defer srv.Close()
+
+	cfg := common.HTTPConfig{
+		Headers:      map[string]string{"X-Test": "1"},
+		BearerToken:  "$TEST_TOKEN",
+		AcceptStatus: []int{http.StatusAccepted},
+	}
+	res, _, err := staticHash(context.Background(), common.Target{URL: srv.URL + "/", HTTP: cfg})
+	if err != nil || res.text != "ok" {
+		t.Fatalf("text=%q err=%v", res.text, err)
+	}
+
+	// 不跟随重定向时 302 不在接受列表内
+	cfg.MaxRedirects = -1
+	if _, _, err = staticHash(context.Background(), common.Target{URL: srv.URL + "/moved", HTTP: cfg}); err == nil {
+		t.Error("不跟随重定向时应返回状态码错误")
+	}
+}
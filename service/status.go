@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"store/common"
+	"sync"
+	"time"
+)
+
+// TargetStatus 一个监控目标的运行状态
+type TargetStatus struct {
+	URL        string    `json:"url"`
+	Mode       string    `json:"mode"`
+	LastCheck  time.Time `json:"last_check,omitempty"`
+	LastChange time.Time `json:"last_change,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	NextRun    time.Time `json:"next_run,omitempty"`
+}
+
+var (
+	statusMu sync.Mutex
+	statuses = map[string]*TargetStatus{}
+)
+
+// updateStatus 在锁内修改目标状态（按 targetKey），不存在时自动创建
+func updateStatus(key string, fn func(s *TargetStatus)) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	s, ok := statuses[key]
+	if !ok {
+		s = &TargetStatus{URL: key}
+		statuses[key] = s
+	}
+	fn(s)
+}
+
+// deleteStatus 移除不再监控的目标
+func deleteStatus(key string) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	delete(statuses, key)
+}
+
+// Statuses 返回所有目标状态的快照，按 URL 和抓取方式排序
+func Statuses() []TargetStatus {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	out := make([]TargetStatus, 0, len(statuses))
+	for _, s := range statuses {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].URL != out[j].URL {
+			return out[i].URL < out[j].URL
+		}
+		return out[i].Mode < out[j].Mode
+	})
+	return out
+}
+
+// StartStatusServer 在 addr 上提供 /status 接口，返回各目标的检查时间和下次运行时间，
+// 以及 /history 接口返回数值时间序列；ctx 取消时关闭
+func StartStatusServer(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(Statuses())
+	})
+	mux.HandleFunc("/history", handleHistory)
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		log.Printf("状态接口已启动: http://%s/status", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("状态接口启动失败: %v", err)
+		}
+	}()
+}
+
+// handleHistory 返回目标的数值时间序列：/history?url=<目标>&name=<提取项>&format=csv，
+// 省略 name 时返回所有提取项，默认输出 JSON
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	target := q.Get("url")
+	if target == "" {
+		http.Error(w, "缺少 url 参数", http.StatusBadRequest)
+		return
+	}
+	// 与 Store 相同的键：static/dynamic 以外的目标需要同时给出 mode
+	if mode := q.Get("mode"); mode != "" {
+		target = targetKey(common.Target{URL: target, Mode: mode})
+	}
+	var st seriesState
+	ok, err := loadState("series", target, &st)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "没有该目标的数据", http.StatusNotFound)
+		return
+	}
+
+	names := make([]string, 0, len(st.Series))
+	for name := range st.Series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if name := q.Get("name"); name != "" {
+		if _, ok := st.Series[name]; !ok {
+			http.Error(w, "没有该提取项的数据", http.StatusNotFound)
+			return
+		}
+		names = []string{name}
+	}
+
+	if q.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		if err := writeSeriesCSV(w, st.Series, names); err != nil {
+			log.Printf("输出 CSV 失败: %v", err)
+		}
+		return
+	}
+	out := make(map[string][]Point, len(names))
+	for _, name := range names {
+		out[name] = st.Series[name]
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(out)
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"log"
+	"os"
+	"path/filepath"
+	"store/common"
+	"sync"
+	"time"
+)
+
+// ErrJobTimeout 页面任务超过单个任务的时间限制
+var ErrJobTimeout = errors.New("浏览器任务超时")
+
+// 超时关闭页面后，任务超过这个时间仍未退出时记录日志
+const jobExitGrace = 5 * time.Second
+
+// BrowserPool 所有 Playwright 任务共用 BrowserManager 提供的浏览器，每个任务使用独立的 BrowserContext，
+// 目标之间、两次检查之间不共享 cookie 和 localStorage；通过信号量限制同时打开的页面数，超出的任务排队等待
+type BrowserPool struct {
+	manager *BrowserManager
+	timeout time.Duration
+	slots   chan struct{}
+
+	mu      sync.Mutex
+	waiting int
+}
+
+func NewBrowserPool(manager *BrowserManager, maxPages int, timeout time.Duration) *BrowserPool {
+	if maxPages < 1 {
+		maxPages = 1
+	}
+	return &BrowserPool{
+		manager: manager,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxPages),
+	}
+}
+
+// Do 排队获取页面槽位，在新建的 BrowserContext 中打开页面执行 fn，结束后关闭 BrowserContext。
+// fn 超过任务时限或 ctx 被取消时页面会被强制关闭，fn 中阻塞的 Playwright 调用随之返回错误
+func (p *BrowserPool) Do(ctx context.Context, fn func(page playwright.Page) error) error {
+	return p.run(ctx, "", fn)
+}
+
+// DoSession 与 Do 相同，但从 statePath 恢复 cookie 和 localStorage，
+// fn 成功后把最新的 StorageState 写回 statePath，供下次检查复用登录状态
+func (p *BrowserPool) DoSession(ctx context.Context, statePath string, fn func(page playwright.Page) error) error {
+	return p.run(ctx, statePath, func(page playwright.Page) error {
+		if err := fn(page); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+			return err
+		}
+		if _, err := page.Context().StorageState(statePath); err != nil {
+			return fmt.Errorf("could not save storage state: %w", err)
+		}
+		return nil
+	})
+}
+
+// open 新建 BrowserContext 和页面，statePath 不为空且文件存在时恢复会话；返回的 cleanup 关闭整个 BrowserContext
+func (p *BrowserPool) open(ctx context.Context, statePath string) (playwright.Page, func(), error) {
+	browser, err := p.manager.Browser(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := playwright.BrowserNewContextOptions{
+		UserAgent: playwright.String(common.UserAgent),
+	}
+	if statePath != "" {
+		if _, err := os.Stat(statePath); err == nil {
+			opts.StorageStatePath = playwright.String(statePath)
+		}
+	}
+	bctx, err := browser.NewContext(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create browser context: %w", err)
+	}
+	page, err := bctx.NewPage()
+	if err != nil {
+		_ = bctx.Close()
+		return nil, nil, fmt.Errorf("could not create page: %w", err)
+	}
+	return page, func() { _ = bctx.Close() }, nil
+}
+
+// run 排队获取槽位后打开页面执行 fn，超时或取消时先关闭页面，再等 fn 退出后返回，
+// 保证返回后 fn 不会再写调用方的变量
+func (p *BrowserPool) run(ctx context.Context, statePath string, fn func(page playwright.Page) error) error {
+	p.mu.Lock()
+	p.waiting++
+	if p.waiting > cap(p.slots) {
+		log.Printf("浏览器页面已满 (%d)，排队任务数: %d", cap(p.slots), p.waiting-cap(p.slots))
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.waiting--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	jobCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	page, cleanup, err := p.open(jobCtx, statePath)
+	if err != nil {
+		return err
+	}
+	page.SetDefaultTimeout(float64(p.timeout.Milliseconds()))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(page)
+	}()
+
+	select {
+	case err = <-done:
+		cleanup()
+	case <-jobCtx.Done():
+		cleanup()
+		select {
+		case <-done:
+		case <-time.After(jobExitGrace):
+			log.Printf("页面已关闭，任务仍未退出，继续等待")
+			<-done
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err = fmt.Errorf("%w (%s)", ErrJobTimeout, p.timeout)
+	}
+	return err
+}
+
+// Close 关闭浏览器
+func (p *BrowserPool) Close() error {
+	return p.manager.Close()
+}
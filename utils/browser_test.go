@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"github.com/playwright-community/playwright-go"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeBrowser struct {
+	playwright.Browser
+
+	mu       sync.Mutex
+	contexts []*fakeContext
+	options  []playwright.BrowserNewContextOptions
+	onClose  func(playwright.Browser)
+	closed   atomic.Bool
+}
+
+func (b *fakeBrowser) IsConnected() bool                          { return !b.closed.Load() }
+func (b *fakeBrowser) OnDisconnected(fn func(playwright.Browser)) { b.onClose = fn }
+func (b *fakeBrowser) Close(...playwright.BrowserCloseOptions) error {
+	b.closed.Store(true)
+	return nil
+}
+
+func (b *fakeBrowser) NewContext(options ...playwright.BrowserNewContextOptions) (playwright.BrowserContext, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := &fakeContext{done: make(chan struct{})}
+	b.contexts = append(b.contexts, c)
+	if len(options) > 0 {
+		b.options = append(b.options, options[0])
+	}
+	return c, nil
+}
+
+// fakeContext Close 后 done 关闭，模拟页面被关闭时阻塞中的 Playwright 调用返回
+type fakeContext struct {
+	playwright.BrowserContext
+	done      chan struct{}
+	closeOnce sync.Once
+	cookies   []playwright.OptionalCookie
+}
+
+func (c *fakeContext) NewPage() (playwright.Page, error) { return &fakePage{ctx: c}, nil }
+func (c *fakeContext) Close(...playwright.BrowserContextCloseOptions) error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+func (c *fakeContext) AddCookies(cookies []playwright.OptionalCookie) error {
+	c.cookies = append(c.cookies, cookies...)
+	return nil
+}
+func (c *fakeContext) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+func (c *fakeContext) StorageState(path ...string) (*playwright.StorageState, error) {
+	if len(path) > 0 {
+		if err := os.WriteFile(path[0], []byte(`{"cookies":[],"origins":[]}`), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return &playwright.StorageState{}, nil
+}
+
+// fakePage 按顺序记录页面操作，如 "goto <url>"、"click <selector>"，用于检查登录和脚本步骤
+type fakePage struct {
+	playwright.Page
+	ctx   *fakeContext
+	url   string
+	calls []string
+	// onGoto 返回跳转后的地址，返回空字符串时停留在请求的地址
+	onGoto func(url string) string
+	// onAction 在每次操作时调用，timeout 为传入的超时毫秒数，返回值作为操作结果
+	onAction func(call string, timeout float64) error
+	// present 选择器匹配的元素数
+	present map[string]int
+}
+
+func (p *fakePage) Context() playwright.BrowserContext { return p.ctx }
+func (p *fakePage) SetDefaultTimeout(float64)          {}
+func (p *fakePage) URL() string                        { return p.url }
+
+func (p *fakePage) do(call string, timeout *float64) error {
+	p.calls = append(p.calls, call)
+	if p.onAction == nil {
+		return nil
+	}
+	var ms float64
+	if timeout != nil {
+		ms = *timeout
+	}
+	return p.onAction(call, ms)
+}
+
+func (p *fakePage) Goto(url string, options ...playwright.PageGotoOptions) (playwright.Response, error) {
+	p.url = url
+	if p.onGoto != nil {
+		if to := p.onGoto(url); to != "" {
+			p.url = to
+		}
+	}
+	return nil, p.do("goto "+url, nil)
+}
+
+func (p *fakePage) Locator(selector string, options ...playwright.PageLocatorOptions) playwright.Locator {
+	return &fakeLocator{page: p, selector: selector}
+}
+
+func (p *fakePage) WaitForSelector(selector string, options ...playwright.PageWaitForSelectorOptions) (playwright.ElementHandle, error) {
+	return nil, p.do("wait_selector "+selector, options[0].Timeout)
+}
+
+func (p *fakePage) WaitForLoadState(options ...playwright.PageWaitForLoadStateOptions) error {
+	return p.do("wait_load", options[0].Timeout)
+}
+
+func (p *fakePage) WaitForTimeout(timeout float64) { _ = p.do("wait_timeout", &timeout) }
+
+func (p *fakePage) Evaluate(expression string, arg ...interface{}) (interface{}, error) {
+	return nil, p.do("evaluate "+expression, nil)
+}
+
+// locator 嵌入字段不能叫 Locator，否则与 Locator 接口的 Locator 方法重名
+type locator = playwright.Locator
+
+type fakeLocator struct {
+	locator
+	page     *fakePage
+	selector string
+}
+
+func (l *fakeLocator) First() playwright.Locator { return l }
+func (l *fakeLocator) Count() (int, error)       { return l.page.present[l.selector], nil }
+func (l *fakeLocator) Click(options ...playwright.LocatorClickOptions) error {
+	return l.page.do("click "+l.selector, options[0].Timeout)
+}
+func (l *fakeLocator) Fill(value string, options ...playwright.LocatorFillOptions) error {
+	return l.page.do("fill "+l.selector+" "+value, options[0].Timeout)
+}
+func (l *fakeLocator) ScrollIntoViewIfNeeded(options ...playwright.LocatorScrollIntoViewIfNeededOptions) error {
+	return l.page.do("scroll "+l.selector, options[0].Timeout)
+}
+
+func newFakePool(t *testing.T, maxPages int, timeout time.Duration) (*BrowserPool, *fakeBrowser) {
+	t.Helper()
+	fb := &fakeBrowser{}
+	m := NewBrowserManager(func() (playwright.Browser, error) { return fb, nil }, time.Millisecond, time.Millisecond)
+	<-m.Start()
+	t.Cleanup(func() { _ = m.Close() })
+	return NewBrowserPool(m, maxPages, timeout), fb
+}
+
+func TestBrowserPoolContextPerJob(t *testing.T) {
+	pool, fb := newFakePool(t, 2, time.Second)
+
+	var pages []*fakePage
+	for i := 0; i < 2; i++ {
+		err := pool.Do(context.Background(), func(page playwright.Page) error {
+			pages = append(pages, page.(*fakePage))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fb.contexts) != 2 {
+		t.Fatalf("期望每个任务新建 BrowserContext, 实际创建 %d 个", len(fb.contexts))
+	}
+	if pages[0].ctx == pages[1].ctx {
+		t.Error("两个任务不应共用 BrowserContext")
+	}
+	for i, c := range fb.contexts {
+		if !c.isClosed() {
+			t.Errorf("任务 %d 结束后 BrowserContext 未关闭", i)
+		}
+	}
+	for _, o := range fb.options {
+		if o.StorageStatePath != nil {
+			t.Error("Do 不应加载会话状态")
+		}
+	}
+}
+
+func TestBrowserPoolQueue(t *testing.T) {
+	pool, _ := newFakePool(t, 1, time.Second)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Do(context.Background(), func(page playwright.Page) error {
+				n := running.Add(1)
+				if n > peak.Load() {
+					peak.Store(n)
+				}
+				time.Sleep(20 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 1 {
+		t.Errorf("maxPages=1 时同时运行的任务数 = %d", peak.Load())
+	}
+}
+
+func TestBrowserPoolTimeoutWaitsForJob(t *testing.T) {
+	pool, _ := newFakePool(t, 1, 50*time.Millisecond)
+
+	// fn 在页面关闭后还会写一次捕获的变量，Do 返回前必须等它结束（配合 -race）
+	var result string
+	err := pool.Do(context.Background(), func(page playwright.Page) error {
+		<-page.(*fakePage).ctx.done
+		time.Sleep(20 * time.Millisecond)
+		result = "done"
+		return errors.New("page closed")
+	})
+	if !errors.Is(err, ErrJobTimeout) {
+		t.Fatalf("期望 ErrJobTimeout, 实际 %v", err)
+	}
+	if result != "done" {
+		t.Error("Do 返回时任务仍在运行")
+	}
+}
+
+func TestDoSession(t *testing.T) {
+	pool, fb := newFakePool(t, 1, time.Second)
+	statePath := filepath.Join(t.TempDir(), "sessions", "example.json")
+
+	run := func() error {
+		return pool.DoSession(context.Background(), statePath, func(page playwright.Page) error { return nil })
+	}
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+	if fb.options[0].StorageStatePath != nil {
+		t.Error("状态文件不存在时不应加载")
+	}
+	if _, err := os.Stat(statePath); err != nil {
+		t.Fatalf("任务成功后应保存会话状态: %v", err)
+	}
+
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+	if p := fb.options[1].StorageStatePath; p == nil || *p != statePath {
+		t.Errorf("第二次任务应从 %s 恢复会话", statePath)
+	}
+
+	// 任务失败时不覆盖已保存的状态
+	_ = os.Remove(statePath)
+	err := pool.DoSession(context.Background(), statePath, func(page playwright.Page) error { return errors.New("login failed") })
+	if err == nil {
+		t.Fatal("期望返回任务错误")
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("任务失败时不应保存会话状态")
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// 对比图中每个变化区域向外扩展的像素
+	comparePadding = 40
+	// 新旧两列之间、上下两个区域之间的间隔
+	compareGap = 12
+	// 单张对比图的最大高度，超过后拆分，保证能以图片形式发送到 Telegram
+	compareMaxHeight = 6000
+	// gif 每帧停留时间（单位 1/100 秒）
+	gifDelay = 100
+)
+
+var (
+	compareBg     = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	compareBorder = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+)
+
+// cropRegions 将变化区域扩展 padding 后裁剪到两张图的公共范围内
+func cropRegions(a, b *image.RGBA, rects []image.Rectangle, padding int) []image.Rectangle {
+	area := a.Bounds().Intersect(b.Bounds())
+	var out []image.Rectangle
+	for _, r := range rects {
+		r = r.Inset(-padding).Intersect(area)
+		if !r.Empty() {
+			out = appendRectMerged(out, r)
+		}
+	}
+	return out
+}
+
+// buildComparisons 生成左旧右新的并排对比图，多个区域从上往下堆叠；
+// 单张图高度超过 maxHeight 时拆分成多张
+func buildComparisons(base, cur *image.RGBA, rects []image.Rectangle, maxHeight int) []*image.RGBA {
+	regions := cropRegions(base, cur, rects, comparePadding)
+	if len(regions) == 0 {
+		return nil
+	}
+
+	var (
+		out   []*image.RGBA
+		chunk []image.Rectangle
+		h     int
+	)
+	flush := func() {
+		if len(chunk) > 0 {
+			out = append(out, sideBySide(base, cur, chunk))
+			chunk, h = nil, 0
+		}
+	}
+	for _, r := range regions {
+		rh := r.Dy() + compareGap
+		if len(chunk) > 0 && h+rh > maxHeight {
+			flush()
+		}
+		chunk = append(chunk, r)
+		h += rh
+	}
+	flush()
+	return out
+}
+
+func sideBySide(base, cur *image.RGBA, regions []image.Rectangle) *image.RGBA {
+	colW := 0
+	height := compareGap
+	for _, r := range regions {
+		colW = max(colW, r.Dx())
+		height += r.Dy() + compareGap
+	}
+	width := colW*2 + compareGap*3
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: compareBg}, image.Point{}, draw.Src)
+
+	y := compareGap
+	for _, r := range regions {
+		left := image.Rect(compareGap, y, compareGap+r.Dx(), y+r.Dy())
+		right := left.Add(image.Pt(colW+compareGap, 0))
+		draw.Draw(canvas, left, base, r.Min, draw.Src)
+		draw.Draw(canvas, right, cur, r.Min, draw.Src)
+		drawRect(canvas, left.Inset(-1), compareBorder, 1)
+		drawRect(canvas, right.Inset(-1), compareBorder, 1)
+		y += r.Dy() + compareGap
+	}
+	return canvas
+}
+
+// buildFlipGIF 生成在旧图和新图之间来回切换的动画，只包含变化区域
+func buildFlipGIF(base, cur *image.RGBA, rects []image.Rectangle) *gif.GIF {
+	regions := cropRegions(base, cur, rects, comparePadding)
+	if len(regions) == 0 {
+		return nil
+	}
+	before := stackRegions(base, regions)
+	after := stackRegions(cur, regions)
+
+	anim := &gif.GIF{}
+	for _, frame := range []*image.RGBA{before, after} {
+		p := image.NewPaletted(frame.Bounds(), palette.WebSafe)
+		draw.FloydSteinberg.Draw(p, p.Bounds(), frame, image.Point{})
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, gifDelay)
+	}
+	return anim
+}
+
+func stackRegions(img *image.RGBA, regions []image.Rectangle) *image.RGBA {
+	width, height := 0, compareGap
+	for _, r := range regions {
+		width = max(width, r.Dx())
+		height += r.Dy() + compareGap
+	}
+	width += compareGap * 2
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: compareBg}, image.Point{}, draw.Src)
+	y := compareGap
+	for _, r := range regions {
+		dst := image.Rect(compareGap, y, compareGap+r.Dx(), y+r.Dy())
+		draw.Draw(canvas, dst, img, r.Min, draw.Src)
+		y += r.Dy() + compareGap
+	}
+	return canvas
+}
+
+func saveGIF(anim *gif.GIF, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.EncodeAll(f, anim)
+}
+
+// fitsTelegramPhoto Telegram sendPhoto 要求宽高之和不超过 10000，宽高比不超过 20
+func fitsTelegramPhoto(img image.Image) bool {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w+h > 10000 {
+		return false
+	}
+	return max(w, h) <= 20*min(w, h)
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestBuildComparisons(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 400, 1000))
+	cur := image.NewRGBA(base.Bounds())
+	red := &image.Uniform{C: color.RGBA{R: 255, A: 255}}
+	draw.Draw(cur, image.Rect(100, 100, 140, 140), red, image.Point{}, draw.Src)
+	draw.Draw(cur, image.Rect(100, 800, 140, 840), red, image.Point{}, draw.Src)
+
+	rects := diffBlocks(base, cur, 20, 8.0)
+	if len(rects) != 2 {
+		t.Fatalf("期望 2 个变化区域, 实际 %d", len(rects))
+	}
+
+	imgs := buildComparisons(base, cur, rects, compareMaxHeight)
+	if len(imgs) != 1 {
+		t.Fatalf("期望 1 张对比图, 实际 %d", len(imgs))
+	}
+	// 每个区域 40+2*padding 高，左右两列
+	wantW := (40+2*comparePadding)*2 + compareGap*3
+	if got := imgs[0].Bounds().Dx(); got != wantW {
+		t.Errorf("对比图宽度 = %d, 期望 %d", got, wantW)
+	}
+
+	// 限制高度后应拆成两张
+	if imgs = buildComparisons(base, cur, rects, 150); len(imgs) != 2 {
+		t.Errorf("期望拆分为 2 张对比图, 实际 %d", len(imgs))
+	}
+
+	anim := buildFlipGIF(base, cur, rects)
+	if anim == nil || len(anim.Image) != 2 {
+		t.Fatal("gif 应包含变化前后两帧")
+	}
+}
+
+func TestChangedPercentAndHeatmap(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	cur := image.NewRGBA(base.Bounds())
+	if p := changedPercent(base, cur); p != 0 {
+		t.Errorf("相同图片变化面积 = %.2f, 期望 0", p)
+	}
+
+	draw.Draw(cur, image.Rect(0, 0, 10, 10), &image.Uniform{C: color.RGBA{R: 255, A: 255}}, image.Point{}, draw.Src)
+	if p := changedPercent(base, cur); p != 1 {
+		t.Errorf("变化面积 = %.2f, 期望 1", p)
+	}
+
+	// 页面变长的部分按已变化计算
+	longer := image.NewRGBA(image.Rect(0, 0, 100, 200))
+	if p := changedPercent(base, longer); p != 50 {
+		t.Errorf("变化面积 = %.2f, 期望 50", p)
+	}
+
+	heat := renderHeatmap(base, cur)
+	if heat.RGBAAt(50, 50) != cur.RGBAAt(50, 50) {
+		t.Error("未变化的像素不应被着色")
+	}
+	if heat.RGBAAt(5, 5) == cur.RGBAAt(5, 5) {
+		t.Error("变化像素应叠加热力色")
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+const (
+	// 单个像素 RGB 的 L1 距离（0..765）超过该值才算变化，过滤抗锯齿等噪声
+	pixelThreshold = 24
+	// 热力图叠加层的最小/最大不透明度（0..255）
+	heatMinAlpha = 90
+	heatMaxAlpha = 220
+)
+
+// pixelDelta 返回两张图同一坐标像素的 RGB L1 距离
+func pixelDelta(a, b *image.RGBA, x, y int) int {
+	i := a.PixOffset(x, y)
+	j := b.PixOffset(x, y)
+	d := 0
+	for k := 0; k < 3; k++ {
+		v := int(a.Pix[i+k]) - int(b.Pix[j+k])
+		if v < 0 {
+			v = -v
+		}
+		d += v
+	}
+	return d
+}
+
+// changedPercent 计算页面面积中发生变化的百分比（0..100）。
+// 两张图尺寸不同时，只存在于其中一张图的部分按已变化计算
+func changedPercent(a, b *image.RGBA) float64 {
+	w := min(a.Bounds().Dx(), b.Bounds().Dx())
+	h := min(a.Bounds().Dy(), b.Bounds().Dy())
+	total := max(a.Bounds().Dx(), b.Bounds().Dx()) * max(a.Bounds().Dy(), b.Bounds().Dy())
+	if total == 0 {
+		return 0
+	}
+
+	changed := total - w*h
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if pixelDelta(a, b, a.Bounds().Min.X+x, a.Bounds().Min.Y+y) > pixelThreshold {
+				changed++
+			}
+		}
+	}
+	return float64(changed) * 100 / float64(total)
+}
+
+// renderHeatmap 在当前截图上叠加半透明热力图，颜色和不透明度随像素变化强度从黄到红递增
+func renderHeatmap(base, cur *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(cur.Bounds())
+	draw.Draw(out, out.Bounds(), cur, cur.Bounds().Min, draw.Src)
+
+	w := min(base.Bounds().Dx(), cur.Bounds().Dx())
+	h := min(base.Bounds().Dy(), cur.Bounds().Dy())
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			d := pixelDelta(base, cur, base.Bounds().Min.X+x, base.Bounds().Min.Y+y)
+			if d <= pixelThreshold {
+				continue
+			}
+			c := heatColor(float64(d-pixelThreshold) / float64(765-pixelThreshold))
+			// 预乘 alpha 的 Over 混合：dst = src + dst*(1-a)
+			i := out.PixOffset(cur.Bounds().Min.X+x, cur.Bounds().Min.Y+y)
+			keep := 255 - uint32(c.A)
+			out.Pix[i+0] = c.R + uint8(uint32(out.Pix[i+0])*keep/255)
+			out.Pix[i+1] = c.G + uint8(uint32(out.Pix[i+1])*keep/255)
+			out.Pix[i+2] = c.B + uint8(uint32(out.Pix[i+2])*keep/255)
+		}
+	}
+	return out
+}
+
+// heatColor 将 0..1 的强度映射为预乘 alpha 的颜色：弱变化偏黄、较透明，强变化偏红、较不透明
+func heatColor(intensity float64) color.RGBA {
+	intensity = min(max(intensity*3, 0), 1) // 放大弱变化，避免大部分区域颜色过淡
+	a := heatMinAlpha + intensity*(heatMaxAlpha-heatMinAlpha)
+	g := 220 * (1 - intensity)
+	return color.RGBA{
+		R: uint8(255 * a / 255),
+		G: uint8(g * a / 255),
+		B: 0,
+		A: uint8(a),
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"net/url"
+	"regexp"
+	"slices"
+	"store/common"
+	"strings"
+)
+
+//go:embed blocklist.txt
+var blocklistFile string
+
+// trackerDomains 内置的统计、广告域名列表
+var trackerDomains = parseDomainList(blocklistFile)
+
+func parseDomainList(s string) []string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, strings.ToLower(line))
+	}
+	return out
+}
+
+// domainBlocked 判断 host 是否属于列表中的域名或其子域名
+func domainBlocked(host string, domains []string) bool {
+	host = strings.ToLower(host)
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp 把 URL 通配符（* 匹配任意字符）转换成正则
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+}
+
+type stubRule struct {
+	pattern *regexp.Regexp
+	file    string
+}
+
+// ApplyIntercept 按目标的拦截策略注册路由：屏蔽资源类型和域名、用本地文件替换指定 URL、注入请求头
+func ApplyIntercept(page playwright.Page, ic *common.Intercept) error {
+	if ic == nil {
+		return nil
+	}
+	var domains []string
+	if ic.BlockTrackers {
+		domains = append(domains, trackerDomains...)
+	}
+	for _, d := range ic.BlockDomains {
+		domains = append(domains, strings.ToLower(d))
+	}
+	var stubs []stubRule
+	for glob, file := range ic.Stubs {
+		re, err := globRegexp(glob)
+		if err != nil {
+			return fmt.Errorf("无效的替换规则 %q: %w", glob, err)
+		}
+		stubs = append(stubs, stubRule{pattern: re, file: file})
+	}
+
+	err := page.Route("**/*", func(route playwright.Route) {
+		req := route.Request()
+		if slices.Contains(ic.BlockTypes, req.ResourceType()) {
+			_ = route.Abort("blockedbyclient")
+			return
+		}
+		if len(domains) > 0 {
+			if u, err := url.Parse(req.URL()); err == nil && domainBlocked(u.Hostname(), domains) {
+				_ = route.Abort("blockedbyclient")
+				return
+			}
+		}
+		for _, s := range stubs {
+			if s.pattern.MatchString(req.URL()) {
+				_ = route.Fulfill(playwright.RouteFulfillOptions{
+					Status: playwright.Int(200),
+					Path:   playwright.String(s.file),
+				})
+				return
+			}
+		}
+		if len(ic.Headers) > 0 {
+			headers := req.Headers()
+			for k, v := range ic.Headers {
+				headers[strings.ToLower(k)] = v
+			}
+			_ = route.Continue(playwright.RouteContinueOptions{Headers: headers})
+			return
+		}
+		_ = route.Continue()
+	})
+	if err != nil {
+		return fmt.Errorf("could not set route: %w", err)
+	}
+	return nil
+}
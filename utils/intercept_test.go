@@ -0,0 +1,27 @@
+package utils
+
+import "testing"
+
+func TestTrackerBlocklist(t *testing.T) {
+	for _, host := range []string{"www.google-analytics.com", "googletagmanager.com", "STATIC.HOTJAR.COM"} {
+		if !domainBlocked(host, trackerDomains) {
+			t.Errorf("%s 应被屏蔽", host)
+		}
+	}
+	for _, host := range []string{"stopelectionrigging.com", "notgoogle-analytics.com"} {
+		if domainBlocked(host, trackerDomains) {
+			t.Errorf("%s 不应被屏蔽", host)
+		}
+	}
+
+	re, err := globRegexp("https://*.example.com/api/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !re.MatchString("https://cdn.example.com/api/v1/items.json") {
+		t.Error("通配符应匹配")
+	}
+	if re.MatchString("https://cdn.example.com/api/items.js") {
+		t.Error("通配符不应匹配")
+	}
+}
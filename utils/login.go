@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"log"
+	"os"
+	"store/common"
+	"strings"
+)
+
+// Navigate 打开页面；配置了登录时先导入 cookie，检测到会话过期则执行登录步骤后重新打开
+func Navigate(page playwright.Page, url string, login *common.Login, opts playwright.PageGotoOptions) error {
+	if login == nil {
+		if _, err := page.Goto(url, opts); err != nil {
+			return fmt.Errorf("could not goto: %w", err)
+		}
+		return nil
+	}
+
+	_, err := os.Stat(login.StatePath(url))
+	hasState := err == nil
+	if !hasState && login.CookiesFile != "" {
+		if err := importCookies(page, login.CookiesFile); err != nil {
+			return err
+		}
+		hasState = true
+	}
+
+	if _, err := page.Goto(url, opts); err != nil {
+		return fmt.Errorf("could not goto: %w", err)
+	}
+	expired, err := sessionExpired(page, login, hasState)
+	if err != nil || !expired {
+		return err
+	}
+
+	if len(login.Steps) == 0 {
+		return fmt.Errorf("%s 会话已过期且未配置登录步骤", url)
+	}
+	log.Printf("%s 会话已过期，重新登录", url)
+	if login.URL != "" {
+		if _, err := page.Goto(login.URL, opts); err != nil {
+			return fmt.Errorf("could not goto login page: %w", err)
+		}
+	}
+	if err := RunSteps(page, login.Steps); err != nil {
+		return fmt.Errorf("登录失败: %w", err)
+	}
+	if _, err := page.Goto(url, opts); err != nil {
+		return fmt.Errorf("could not goto: %w", err)
+	}
+	// 登录后必须能通过过期检测，否则视为登录失败
+	if expired, err = sessionExpired(page, login, true); err != nil {
+		return err
+	}
+	if expired {
+		return fmt.Errorf("%s 登录后会话仍无效", url)
+	}
+	log.Printf("%s 登录成功", url)
+	return nil
+}
+
+// sessionExpired 根据跳转地址或页面元素判断会话是否过期，未配置检测规则时以是否已有会话为准
+func sessionExpired(page playwright.Page, login *common.Login, hasState bool) (bool, error) {
+	if login.ExpiredURL == "" && login.ExpiredSelector == "" {
+		return !hasState, nil
+	}
+	if login.ExpiredURL != "" && strings.Contains(page.URL(), login.ExpiredURL) {
+		return true, nil
+	}
+	if login.ExpiredSelector != "" {
+		n, err := page.Locator(login.ExpiredSelector).Count()
+		if err != nil {
+			return false, fmt.Errorf("could not check session: %w", err)
+		}
+		return n > 0, nil
+	}
+	return false, nil
+}
+
+// importCookies 导入 cookie 文件，支持 cookie 数组或带 cookies 字段的 StorageState
+func importCookies(page playwright.Page, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取 cookie 文件失败: %w", err)
+	}
+	var cookies []playwright.OptionalCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		var state struct {
+			Cookies []playwright.OptionalCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("解析 cookie 文件失败: %w", err)
+		}
+		cookies = state.Cookies
+	}
+	if err := page.Context().AddCookies(cookies); err != nil {
+		return fmt.Errorf("导入 cookie 失败: %w", err)
+	}
+	log.Printf("已导入 %d 个 cookie: %s", len(cookies), path)
+	return nil
+}
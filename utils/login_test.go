@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"github.com/playwright-community/playwright-go"
+	"os"
+	"path/filepath"
+	"reflect"
+	"store/common"
+	"strings"
+	"testing"
+)
+
+const (
+	loginTarget = "https://bkokfi.com/account"
+	loginPage   = "https://bkokfi.com/login"
+)
+
+// newLoginPage 模拟需要登录的站点：未登录时打开目标页跳转到登录页，点击 #submit 后登录成功
+func newLoginPage(loginWorks bool) *fakePage {
+	loggedIn := false
+	p := &fakePage{ctx: &fakeContext{done: make(chan struct{})}}
+	p.onGoto = func(url string) string {
+		if url == loginTarget && !loggedIn {
+			return loginPage + "?next=/account"
+		}
+		return ""
+	}
+	p.onAction = func(call string, timeout float64) error {
+		if call == "click #submit" && loginWorks {
+			loggedIn = true
+		}
+		return nil
+	}
+	return p
+}
+
+func newLogin(t *testing.T) *common.Login {
+	return &common.Login{
+		URL: loginPage,
+		Steps: []common.Step{
+			{Action: common.StepFill, Selector: "#user", Value: "monitor"},
+			{Action: common.StepClick, Selector: "#submit"},
+		},
+		StateFile:  filepath.Join(t.TempDir(), "bkokfi.json"),
+		ExpiredURL: "/login",
+	}
+}
+
+func TestNavigateRelogin(t *testing.T) {
+	page := newLoginPage(true)
+	if err := Navigate(page, loginTarget, newLogin(t), playwright.PageGotoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"goto " + loginTarget,
+		"goto " + loginPage,
+		"fill #user monitor",
+		"click #submit",
+		"goto " + loginTarget,
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Errorf("calls = %q", page.calls)
+	}
+}
+
+func TestNavigateSessionValid(t *testing.T) {
+	login := newLogin(t)
+	login.ExpiredURL = ""
+	login.ExpiredSelector = "#login-form"
+	if err := os.WriteFile(login.StateFile, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 页面上没有登录表单，会话有效，不执行登录步骤
+	page := &fakePage{ctx: &fakeContext{}}
+	if err := Navigate(page, loginTarget, login, playwright.PageGotoOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.calls, []string{"goto " + loginTarget}) {
+		t.Errorf("calls = %q", page.calls)
+	}
+
+	// 出现登录表单视为过期
+	page.present = map[string]int{"#login-form": 1}
+	expired, err := sessionExpired(page, login, true)
+	if err != nil || !expired {
+		t.Errorf("expired = %v, err = %v", expired, err)
+	}
+}
+
+func TestNavigateStillExpired(t *testing.T) {
+	page := newLoginPage(false)
+	err := Navigate(page, loginTarget, newLogin(t), playwright.PageGotoOptions{})
+	if err == nil || !strings.Contains(err.Error(), "登录后会话仍无效") {
+		t.Fatalf("err = %v", err)
+	}
+
+	login := newLogin(t)
+	login.Steps = nil
+	err = Navigate(newLoginPage(true), loginTarget, login, playwright.PageGotoOptions{})
+	if err == nil || !strings.Contains(err.Error(), "未配置登录步骤") {
+		t.Errorf("未配置登录步骤时 err = %v", err)
+	}
+}
+
+func TestNavigateImportCookies(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"array.json": `[{"name":"sid","value":"1","domain":"bkokfi.com","path":"/"}]`,
+		"state.json": `{"cookies":[{"name":"sid","value":"1","domain":"bkokfi.com","path":"/"}],"origins":[]}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// 未配置过期检测时，导入 cookie 即视为已有会话，不执行登录步骤
+		login := newLogin(t)
+		login.ExpiredURL = ""
+		login.CookiesFile = path
+		page := &fakePage{ctx: &fakeContext{}}
+		if err := Navigate(page, loginTarget, login, playwright.PageGotoOptions{}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(page.ctx.cookies) != 1 || page.ctx.cookies[0].Name != "sid" {
+			t.Errorf("%s: cookies = %+v", name, page.ctx.cookies)
+		}
+		if len(page.calls) != 1 {
+			t.Errorf("%s: calls = %q", name, page.calls)
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`"sid=1"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := importCookies(&fakePage{ctx: &fakeContext{}}, bad); err == nil {
+		t.Error("格式错误的 cookie 文件应返回错误")
+	}
+}
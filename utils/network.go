@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NetworkSnapshot 一次页面加载中的网络请求概况
+type NetworkSnapshot struct {
+	// Domains 请求过的所有域名
+	Domains []string `json:"domains"`
+	// Scripts 脚本地址（去掉查询参数）到内容 sha256，读取内容失败时为空字符串
+	Scripts map[string]string `json:"scripts"`
+	// Failed 失败的请求地址到失败原因或状态码
+	Failed map[string]string `json:"failed"`
+}
+
+// NetworkRecorder 监听页面的请求和响应
+type NetworkRecorder struct {
+	mu      sync.Mutex
+	domains map[string]bool
+	scripts map[string]playwright.Response
+	failed  map[string]string
+}
+
+// RecordNetwork 在打开页面前调用，开始记录请求
+func RecordNetwork(page playwright.Page) *NetworkRecorder {
+	r := &NetworkRecorder{
+		domains: map[string]bool{},
+		scripts: map[string]playwright.Response{},
+		failed:  map[string]string{},
+	}
+	page.OnRequest(r.onRequest)
+	page.OnResponse(r.onResponse)
+	page.OnRequestFailed(r.onRequestFailed)
+	return r
+}
+
+// 以下回调在 playwright 连接的事件分发协程中执行，只能短暂持锁，不能调用需要等待驱动回复的方法
+
+func (r *NetworkRecorder) onRequest(req playwright.Request) {
+	if u, err := url.Parse(req.URL()); err == nil && u.Hostname() != "" {
+		r.mu.Lock()
+		r.domains[strings.ToLower(u.Hostname())] = true
+		r.mu.Unlock()
+	}
+}
+
+func (r *NetworkRecorder) onResponse(resp playwright.Response) {
+	key := stripQuery(resp.URL())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if resp.Status() >= 400 {
+		r.failed[key] = fmt.Sprintf("HTTP %d", resp.Status())
+		return
+	}
+	if resp.Request().ResourceType() == "script" {
+		r.scripts[key] = resp
+	}
+}
+
+func (r *NetworkRecorder) onRequestFailed(req playwright.Request) {
+	reason := "failed"
+	if err := req.Failure(); err != nil {
+		reason = err.Error()
+	}
+	// 被拦截策略主动屏蔽的请求不算失败
+	if strings.Contains(reason, "ERR_BLOCKED_BY_CLIENT") {
+		return
+	}
+	r.mu.Lock()
+	r.failed[stripQuery(req.URL())] = reason
+	r.mu.Unlock()
+}
+
+// Snapshot 汇总记录结果并计算脚本内容哈希，需在页面关闭前调用。
+// 只在复制记录时持锁：resp.Body() 要等驱动回复，而回复和网络事件由同一个协程分发，
+// 持锁调用时新到的事件会卡在锁上，Body() 永远等不到回复
+func (r *NetworkRecorder) Snapshot() *NetworkSnapshot {
+	snap := &NetworkSnapshot{
+		Scripts: map[string]string{},
+		Failed:  map[string]string{},
+	}
+	r.mu.Lock()
+	for d := range r.domains {
+		snap.Domains = append(snap.Domains, d)
+	}
+	scripts := make(map[string]playwright.Response, len(r.scripts))
+	for u, resp := range r.scripts {
+		scripts[u] = resp
+	}
+	for u, reason := range r.failed {
+		snap.Failed[u] = reason
+	}
+	r.mu.Unlock()
+
+	sort.Strings(snap.Domains)
+	for u, resp := range scripts {
+		body, err := resp.Body()
+		if err != nil {
+			// 内容读取失败也要记录地址，否则下次读取成功时会被误报为新增脚本
+			snap.Scripts[u] = ""
+			continue
+		}
+		sum := sha256.Sum256(body)
+		snap.Scripts[u] = hex.EncodeToString(sum[:])
+	}
+	return snap
+}
+
+// stripQuery 去掉查询参数和锚点，避免带版本号的地址每次都算作新请求
+func stripQuery(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"github.com/playwright-community/playwright-go"
+	"testing"
+	"time"
+)
+
+type fakeRequest struct {
+	playwright.Request
+	url, resourceType string
+}
+
+func (f *fakeRequest) URL() string          { return f.url }
+func (f *fakeRequest) ResourceType() string { return f.resourceType }
+
+// fakeResponse Body() 模拟 playwright 的事件分发：等待期间分发协程还要投递新的网络事件，
+// 事件处理完之前不会返回结果
+type fakeResponse struct {
+	playwright.Response
+	req     *fakeRequest
+	status  int
+	body    string
+	bodyErr error
+	onFetch func()
+}
+
+func (f *fakeResponse) URL() string                 { return f.req.url }
+func (f *fakeResponse) Status() int                 { return f.status }
+func (f *fakeResponse) Request() playwright.Request { return f.req }
+func (f *fakeResponse) Body() ([]byte, error) {
+	if f.onFetch != nil {
+		done := make(chan struct{})
+		go func() {
+			f.onFetch()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			return nil, errors.New("事件分发被阻塞")
+		}
+	}
+	if f.bodyErr != nil {
+		return nil, f.bodyErr
+	}
+	return []byte(f.body), nil
+}
+
+func TestSnapshotDuringEvents(t *testing.T) {
+	r := &NetworkRecorder{
+		domains: map[string]bool{},
+		scripts: map[string]playwright.Response{},
+		failed:  map[string]string{},
+	}
+	script := &fakeResponse{
+		req:    &fakeRequest{url: "https://bkokfi.com/app.js?v=1", resourceType: "script"},
+		status: 200,
+		body:   "console.log(1)",
+	}
+	script.onFetch = func() {
+		r.onRequest(&fakeRequest{url: "https://cdn.example.com/late.js"})
+		r.onResponse(&fakeResponse{req: &fakeRequest{url: "https://bkokfi.com/api", resourceType: "fetch"}, status: 500})
+	}
+	r.onRequest(script.req)
+	r.onResponse(script)
+
+	snap := r.Snapshot()
+	if _, ok := snap.Scripts["https://bkokfi.com/app.js"]; !ok {
+		t.Fatalf("Snapshot 期间的网络事件被阻塞: %+v", snap)
+	}
+	if len(snap.Domains) != 1 || snap.Domains[0] != "bkokfi.com" {
+		t.Errorf("domains = %v", snap.Domains)
+	}
+	// 快照之后到达的事件记录在下一次快照中
+	if next := r.Snapshot(); next.Failed["https://bkokfi.com/api"] != "HTTP 500" {
+		t.Errorf("failed = %v", next.Failed)
+	}
+}
+
+func TestSnapshotKeepsFailedScripts(t *testing.T) {
+	r := &NetworkRecorder{
+		domains: map[string]bool{},
+		scripts: map[string]playwright.Response{},
+		failed:  map[string]string{},
+	}
+	r.onResponse(&fakeResponse{
+		req:     &fakeRequest{url: "https://bkokfi.com/app.js", resourceType: "script"},
+		status:  200,
+		bodyErr: errors.New("response body is unavailable for redirect responses"),
+	})
+
+	// 读取内容失败的脚本保留地址、不带哈希
+	h, ok := r.Snapshot().Scripts["https://bkokfi.com/app.js"]
+	if !ok || h != "" {
+		t.Errorf("scripts[app.js] = %q, %v", h, ok)
+	}
+}
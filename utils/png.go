@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/playwright-community/playwright-go"
 	"image"
@@ -14,19 +15,26 @@ import (
 	"store/common"
 )
 
-func SaveAndDiff(bot *TelegramBot, browser playwright.Browser, url string) error {
-	pngBytes, err := playwrightWithNet(browser, url)
+// SaveAndDiff 截图并与基线比较，变化面积达到 PngAlertPercent 阈值时发送差异图和对比图
+func SaveAndDiff(ctx context.Context, bot *TelegramBot, pool *BrowserPool, t common.Target) error {
+	url := t.URL
+	pngBytes, boxes, err := playwrightWithNet(ctx, pool, t)
 	if err != nil {
 		fmt.Printf("screenshot failed: %v\n", err)
 		return err
 	}
 	// 初始化基线图片
 	baselinePath := filepath.Join(common.PngDir[url], "baseline.png")
+	baselineTextPath := filepath.Join(common.PngDir[url], "baseline.json")
 	if _, err = os.Stat(baselinePath); os.IsNotExist(err) {
 		if err = savePNG(pngBytes, baselinePath); err != nil {
 			fmt.Printf("save baseline failed: %v\n", err)
 			return err
 		}
+		if err = saveTextBoxes(boxes, baselineTextPath); err != nil {
+			fmt.Printf("save baseline text failed: %v\n", err)
+			return err
+		}
 		log.Printf("基线不存在，已初始化基线: %s", baselinePath)
 		return nil
 	}
@@ -38,6 +46,13 @@ func SaveAndDiff(bot *TelegramBot, browser playwright.Browser, url string) error
 		return err
 	}
 
+	// 读取基线对应的文本位置
+	baseBoxes, err := loadTextBoxes(baselineTextPath)
+	if err != nil {
+		fmt.Printf("read baseline text failed: %v\n", err)
+		return err
+	}
+
 	// 解析基线图
 	baseImg, err := decodePNG(baseBytes)
 	if err != nil {
@@ -53,19 +68,36 @@ func SaveAndDiff(bot *TelegramBot, browser playwright.Browser, url string) error
 
 	// 计算图片差异
 	rects := diffBlocks(baseImg, curImg, 20, 8.0)
+
+	// 计算变化面积占比，低于阈值时不发送差异图，也不更新基线
+	var percent float64
+	if len(rects) > 0 {
+		percent = changedPercent(baseImg, curImg)
+	}
+	if threshold := common.PngAlertPercent[url]; threshold > 0 && percent < threshold {
+		log.Printf("变化面积 %.2f%% 低于告警阈值 %.2f%%, 忽略", percent, threshold)
+		return nil
+	}
 	if len(rects) == 0 {
 		log.Printf("未检测到显著变化 (阈值=%.2f, 块大小=%d)", 8.0, 20)
 		return nil
 	}
 
-	// 绘画红线
-	annotated := image.NewRGBA(curImg.Bounds())
-	draw.Draw(annotated, annotated.Bounds(), curImg, image.Point{}, draw.Src)
-	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
-	for _, r := range rects {
-		// expand a bit for visibility
-		exp := r.Inset(-6)
-		drawRect(annotated, exp, red, 3)
+	var annotated *image.RGBA
+	switch common.PngDiffMode[url] {
+	case common.DiffModeHeatmap:
+		// 叠加热力图
+		annotated = renderHeatmap(baseImg, curImg)
+	default:
+		// 绘画红线
+		annotated = image.NewRGBA(curImg.Bounds())
+		draw.Draw(annotated, annotated.Bounds(), curImg, image.Point{}, draw.Src)
+		red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+		for _, r := range rects {
+			// expand a bit for visibility
+			exp := r.Inset(-6)
+			drawRect(annotated, exp, red, 3)
+		}
 	}
 
 	// 保存绘画后的图片
@@ -79,59 +111,166 @@ func SaveAndDiff(bot *TelegramBot, browser playwright.Browser, url string) error
 		fmt.Printf("save diff failed: %v\n", err)
 		return err
 	}
-	log.Printf("检测到变化: %d 个区域. 差异图已保存: %s", len(rects), diffPath)
+	log.Printf("检测到变化: %d 个区域, 变化面积 %.2f%%. 差异图已保存: %s", len(rects), percent, diffPath)
 	// 更新基线
 	prevPath := filepath.Join(common.PngDir[url], "prev.png")
 	if err = savePNG(baseBytes, prevPath); err != nil {
 		fmt.Printf("save diff failed: %v\n", err)
 		return err
 	}
+	if err = saveTextBoxes(baseBoxes, filepath.Join(common.PngDir[url], "prev.json")); err != nil {
+		fmt.Printf("save prev text failed: %v\n", err)
+		return err
+	}
 	log.Printf("已备份旧基线为: %s", prevPath)
 	if err = savePNG(pngBytes, baselinePath); err != nil {
 		fmt.Printf("update baseline failed: %v\n", err)
 		return err
 	}
+	if err = saveTextBoxes(boxes, baselineTextPath); err != nil {
+		fmt.Printf("update baseline text failed: %v\n", err)
+		return err
+	}
 	log.Printf("基线已更新")
 	// tg消息推送
-	if err = bot.SendDocument(diffPath, fmt.Sprintf("检测到变化: %d 个区域", len(rects))); err != nil {
+	if err = bot.SendDocument(ctx, diffPath, fmt.Sprintf("检测到变化: %d 个区域, 变化面积 %.2f%%", len(rects), percent)); err != nil {
 		log.Printf("发送图片到TG失败: %v", err)
 		return err
 	}
+	// 对比图只是辅助信息，失败不影响基线更新
+	if err = sendComparisons(ctx, bot, url, baseImg, curImg, rects); err != nil {
+		log.Printf("发送对比图到TG失败: %v", err)
+	}
+	// 把变化区域映射回页面文本，列出前后文本；所有区域文本都没变（纯样式、图片变化）时不发送
+	if regions := regionTexts(baseBoxes, boxes, rects); anyTextChanged(regions) {
+		if err = bot.SendMessage(ctx, formatRegionTexts(regions)); err != nil {
+			log.Printf("发送区域文本到TG失败: %v", err)
+		}
+	}
 	return nil
 }
 
-func playwrightWithNet(browser playwright.Browser, urlStr string) ([]byte, error) {
-	// 新建页面
-	page, err := browser.NewPage()
-	if err != nil {
-		return nil, fmt.Errorf("could not create page: %w", err)
+// sendComparisons 生成并发送变化区域的新旧并排对比图，按配置附带 gif 动图
+func sendComparisons(ctx context.Context, bot *TelegramBot, url string, baseImg, curImg *image.RGBA, rects []image.Rectangle) error {
+	dir := common.PngDir[url]
+	// 清理上一次留下的对比图，避免拆分数量变少时残留旧文件
+	old, _ := filepath.Glob(filepath.Join(dir, "compare_*.png"))
+	for _, p := range old {
+		_ = os.Remove(p)
 	}
-	defer page.Close()
 
-	// 设置视口大小
-	if err := page.SetViewportSize(common.PngView[urlStr].Width, common.PngView[urlStr].Height); err != nil {
-		return nil, fmt.Errorf("could not set viewport: %w", err)
+	var photos, documents []string
+	for i, img := range buildComparisons(baseImg, curImg, rects, compareMaxHeight) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return fmt.Errorf("encode compare failed: %w", err)
+		}
+		p := filepath.Join(dir, fmt.Sprintf("compare_%d.png", i+1))
+		if err := savePNG(buf.Bytes(), p); err != nil {
+			return fmt.Errorf("save compare failed: %w", err)
+		}
+		// 超出 Telegram 图片尺寸限制的按文件发送
+		if fitsTelegramPhoto(img) {
+			photos = append(photos, p)
+		} else {
+			documents = append(documents, p)
+		}
 	}
+	log.Printf("对比图已保存: %d 张", len(photos)+len(documents))
 
-	// 打开页面并等待网络空闲
-	_, err = page.Goto(urlStr, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(30000),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("could not navigate: %w", err)
+	caption := "变化区域对比（左：变化前，右：变化后）"
+	for len(photos) > 0 {
+		n := min(len(photos), 10)
+		var err error
+		if n == 1 {
+			err = bot.SendPhoto(ctx, photos[0], caption)
+		} else {
+			err = bot.SendMediaGroup(ctx, photos[:n], caption)
+		}
+		if err != nil {
+			return err
+		}
+		photos = photos[n:]
+	}
+	for _, p := range documents {
+		if err := bot.SendDocument(ctx, p, caption); err != nil {
+			return err
+		}
+	}
+
+	if !common.PngGif[url] {
+		return nil
+	}
+	anim := buildFlipGIF(baseImg, curImg, rects)
+	if anim == nil {
+		return nil
+	}
+	gifPath := filepath.Join(dir, "compare.gif")
+	if err := saveGIF(anim, gifPath); err != nil {
+		return fmt.Errorf("save gif failed: %w", err)
+	}
+	return bot.SendAnimation(ctx, gifPath, "变化前后切换")
+}
+
+func playwrightWithNet(ctx context.Context, pool *BrowserPool, t common.Target) ([]byte, []TextBox, error) {
+	urlStr := t.URL
+	var (
+		buf   []byte
+		boxes []TextBox
+	)
+	job := func(page playwright.Page) error {
+		// 设置视口大小
+		if err := page.SetViewportSize(common.PngView[urlStr].Width, common.PngView[urlStr].Height); err != nil {
+			return fmt.Errorf("could not set viewport: %w", err)
+		}
+
+		// 按配置屏蔽资源、替换请求
+		err := ApplyIntercept(page, t.Intercept)
+		if err != nil {
+			return err
+		}
+
+		// 打开页面并等待就绪，需要登录时自动处理会话
+		err = Navigate(page, urlStr, t.Login, GotoOptions(t.Wait))
+		if err != nil {
+			return err
+		}
+		if err = WaitReady(page, t.Wait); err != nil {
+			return err
+		}
+		// 执行预置的页面操作
+		if err = RunSteps(page, t.Steps); err != nil {
+			return err
+		}
+
+		// 采集可见文本及位置，用于把差异区域映射回文本
+		boxes, err = collectTextBoxes(page)
+		if err != nil {
+			return err
+		}
+
+		// 获取截图的字节数组
+		buf, err = page.Screenshot(playwright.PageScreenshotOptions{
+			FullPage: playwright.Bool(true),
+		})
+		if err != nil {
+			return fmt.Errorf("could not take screenshot: %w", err)
+		}
+		return nil
 	}
 
-	// 获取截图的字节数组
-	buf, err := page.Screenshot(playwright.PageScreenshotOptions{
-		FullPage: playwright.Bool(true),
-	})
+	var err error
+	if t.Login != nil {
+		err = pool.DoSession(ctx, t.Login.StatePath(urlStr), job)
+	} else {
+		err = pool.Do(ctx, job)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("could not take screenshot: %w", err)
+		return nil, nil, err
 	}
 
 	log.Println("截图已获取")
-	return buf, nil
+	return buf, boxes, nil
 }
 
 func savePNG(pngBytes []byte, path string) error {
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/playwright-community/playwright-go"
 	"image"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"store/common"
 	"testing"
+	"time"
 )
 
 func Test(t *testing.T) {
@@ -24,15 +26,16 @@ func Test(t *testing.T) {
 	defer pw.Stop()
 
 	// 启动 Chromium 浏览器（headless）
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-	})
-	if err != nil {
-		log.Fatalf("could not launch browser: %v", err)
-	}
-	defer browser.Close()
+	manager := NewBrowserManager(func() (playwright.Browser, error) {
+		return pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+			Headless: playwright.Bool(true),
+		})
+	}, time.Second, time.Second)
+	<-manager.Start()
+	pool := NewBrowserPool(manager, 1, time.Minute)
+	defer pool.Close()
 	// 获取网站截图
-	pngBytes, err := playwrightWithNet(browser, url)
+	pngBytes, _, err := playwrightWithNet(context.Background(), pool, common.Target{URL: url})
 	if err != nil {
 		fmt.Printf("screenshot failed: %v\n", err)
 		return
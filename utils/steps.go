@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"log"
+	"os"
+	"store/common"
+	"time"
+)
+
+const defaultStepTimeout = 10 * time.Second
+
+// scrollToBottomJS 逐屏向下滚动直到页面底部，每屏等待一会儿让懒加载内容出现，最后回到顶部
+const scrollToBottomJS = `async () => {
+	for (let i = 0; i < 200; i++) {
+		window.scrollBy(0, window.innerHeight);
+		await new Promise(r => setTimeout(r, 250));
+		if (window.scrollY + window.innerHeight >= document.documentElement.scrollHeight) break;
+	}
+	window.scrollTo(0, 0);
+}`
+
+// RunSteps 依次执行页面操作，非 Optional 的步骤失败即返回
+func RunSteps(page playwright.Page, steps []common.Step) error {
+	for i, st := range steps {
+		if err := runStep(page, st); err != nil {
+			if st.Optional {
+				log.Printf("可选步骤 %d %s %s 跳过: %v", i+1, st.Action, st.Selector, err)
+				continue
+			}
+			return fmt.Errorf("第 %d 步 %s %s 失败: %w", i+1, st.Action, st.Selector, err)
+		}
+	}
+	return nil
+}
+
+func runStep(page playwright.Page, st common.Step) error {
+	timeout := st.Timeout
+	if timeout <= 0 {
+		timeout = defaultStepTimeout
+	}
+	ms := playwright.Float(float64(timeout.Milliseconds()))
+
+	switch st.Action {
+	case common.StepClick:
+		return page.Locator(st.Selector).First().Click(playwright.LocatorClickOptions{Timeout: ms})
+	case common.StepFill:
+		return page.Locator(st.Selector).First().Fill(os.ExpandEnv(st.Value), playwright.LocatorFillOptions{Timeout: ms})
+	case common.StepWaitSelector:
+		_, err := page.WaitForSelector(st.Selector, playwright.PageWaitForSelectorOptions{Timeout: ms})
+		return err
+	case common.StepWaitLoad:
+		return page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State:   playwright.LoadStateNetworkidle,
+			Timeout: ms,
+		})
+	case common.StepWaitTimeout:
+		page.WaitForTimeout(float64(timeout.Milliseconds()))
+		return nil
+	case common.StepScroll:
+		if st.Selector != "" {
+			return page.Locator(st.Selector).First().ScrollIntoViewIfNeeded(playwright.LocatorScrollIntoViewIfNeededOptions{Timeout: ms})
+		}
+		_, err := page.Evaluate(scrollToBottomJS)
+		return err
+	case common.StepEvaluate:
+		_, err := page.Evaluate(st.Value)
+		return err
+	default:
+		return fmt.Errorf("未知操作类型: %s", st.Action)
+	}
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"store/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStepsOrder(t *testing.T) {
+	t.Setenv("MONITOR_PASSWORD", "secret")
+	page := &fakePage{ctx: &fakeContext{}}
+	steps := []common.Step{
+		{Action: common.StepFill, Selector: "#password", Value: "$MONITOR_PASSWORD"},
+		{Action: common.StepClick, Selector: "#submit"},
+		{Action: common.StepWaitLoad},
+		{Action: common.StepWaitSelector, Selector: ".dashboard"},
+		{Action: common.StepScroll, Selector: "footer"},
+		{Action: common.StepEvaluate, Value: "window.stop()"},
+	}
+	if err := RunSteps(page, steps); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"fill #password secret",
+		"click #submit",
+		"wait_load",
+		"wait_selector .dashboard",
+		"scroll footer",
+		"evaluate window.stop()",
+	}
+	if !reflect.DeepEqual(page.calls, want) {
+		t.Errorf("calls = %q", page.calls)
+	}
+}
+
+func TestRunStepsOptional(t *testing.T) {
+	page := &fakePage{ctx: &fakeContext{}}
+	page.onAction = func(call string, timeout float64) error {
+		if call == "click .cookie-banner" || call == "click #missing" {
+			return errors.New("element not found")
+		}
+		return nil
+	}
+
+	// 可选步骤失败时继续执行后面的步骤
+	steps := []common.Step{
+		{Action: common.StepClick, Selector: ".cookie-banner", Optional: true},
+		{Action: common.StepClick, Selector: "#menu"},
+	}
+	if err := RunSteps(page, steps); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.calls, []string{"click .cookie-banner", "click #menu"}) {
+		t.Errorf("calls = %q", page.calls)
+	}
+
+	// 必需步骤失败时立即返回，后面的步骤不再执行
+	page.calls = nil
+	steps = []common.Step{
+		{Action: common.StepClick, Selector: "#missing"},
+		{Action: common.StepClick, Selector: "#menu"},
+	}
+	err := RunSteps(page, steps)
+	if err == nil || !strings.Contains(err.Error(), "第 1 步 click #missing 失败") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(page.calls) != 1 {
+		t.Errorf("calls = %q", page.calls)
+	}
+}
+
+func TestRunStepsUnknownAction(t *testing.T) {
+	page := &fakePage{ctx: &fakeContext{}}
+	err := RunSteps(page, []common.Step{{Action: "hover", Selector: "#menu"}})
+	if err == nil || !strings.Contains(err.Error(), "未知操作类型: hover") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunStepsTimeout(t *testing.T) {
+	page := &fakePage{ctx: &fakeContext{}}
+	timeouts := map[string]float64{}
+	page.onAction = func(call string, timeout float64) error {
+		timeouts[call] = timeout
+		if call == "wait_selector .slow" {
+			return errors.New("Timeout 500ms exceeded")
+		}
+		return nil
+	}
+
+	steps := []common.Step{
+		{Action: common.StepClick, Selector: "#menu"},
+		{Action: common.StepWaitSelector, Selector: ".slow", Timeout: 500 * time.Millisecond},
+	}
+	err := RunSteps(page, steps)
+	if err == nil || !strings.Contains(err.Error(), "第 2 步 wait_selector .slow 失败") {
+		t.Fatalf("err = %v", err)
+	}
+	// 未配置超时的步骤使用默认值，配置了的按步骤生效
+	want := map[string]float64{
+		"click #menu":         float64(defaultStepTimeout.Milliseconds()),
+		"wait_selector .slow": 500,
+	}
+	if !reflect.DeepEqual(timeouts, want) {
+		t.Errorf("timeouts = %v", timeouts)
+	}
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrBrowserClosed 浏览器管理器已关闭
+var ErrBrowserClosed = errors.New("浏览器已关闭")
+
+// BrowserManager 负责 Chromium 的启动和看护：浏览器崩溃或断开（OnDisconnected）时
+// 按指数退避重新启动，调用方每次通过 Browser 取到的都是当前可用的句柄
+type BrowserManager struct {
+	launch     func() (playwright.Browser, error)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	done       chan struct{}
+
+	mu       sync.Mutex
+	browser  playwright.Browser
+	ready    chan struct{} // 重启完成（或管理器关闭）时关闭，nil 表示没有进行中的重启
+	lastErr  error
+	closed   bool
+	launches int
+}
+
+func NewBrowserManager(launch func() (playwright.Browser, error), minBackoff, maxBackoff time.Duration) *BrowserManager {
+	return &BrowserManager{
+		launch:     launch,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		done:       make(chan struct{}),
+	}
+}
+
+// Start 在后台启动浏览器，返回的 channel 在首次启动成功或管理器关闭时关闭
+func (m *BrowserManager) Start() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.restartLocked()
+}
+
+// Browser 返回当前浏览器；浏览器不可用时触发重启，并在 ctx 结束前等待重启完成
+func (m *BrowserManager) Browser(ctx context.Context) (playwright.Browser, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrBrowserClosed
+	}
+	if m.browser != nil && m.browser.IsConnected() {
+		browser := m.browser
+		m.mu.Unlock()
+		return browser, nil
+	}
+	ready := m.restartLocked()
+	m.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrBrowserClosed
+	}
+	if m.browser == nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("等待浏览器重启: %w (%v)", ctx.Err(), m.lastErr)
+		}
+		return nil, fmt.Errorf("浏览器重启中: %w", m.lastErr)
+	}
+	return m.browser, nil
+}
+
+// restartLocked 丢弃当前浏览器并在后台重启，已有重启在进行时直接复用，调用方需持有锁
+func (m *BrowserManager) restartLocked() chan struct{} {
+	if m.ready != nil {
+		return m.ready
+	}
+	old := m.browser
+	m.browser = nil
+	m.ready = make(chan struct{})
+	m.lastErr = errors.New("浏览器尚未启动")
+	go m.relaunch(old, m.ready)
+	return m.ready
+}
+
+func (m *BrowserManager) relaunch(old playwright.Browser, ready chan struct{}) {
+	defer close(ready)
+	if old != nil {
+		_ = old.Close()
+	}
+
+	backoff := m.minBackoff
+	for attempt := 1; ; attempt++ {
+		browser, err := m.start()
+
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			if err == nil {
+				_ = browser.Close()
+			}
+			return
+		}
+		if err == nil {
+			m.browser, m.ready, m.lastErr = browser, nil, nil
+			m.launches++
+			n := m.launches
+			m.mu.Unlock()
+			if n == 1 {
+				log.Println("Chromium 已启动")
+			} else {
+				log.Printf("Chromium 已重新启动 (累计启动 %d 次, 本轮第 %d 次尝试)", n, attempt)
+			}
+			return
+		}
+		m.lastErr = err
+		m.mu.Unlock()
+
+		log.Printf("Chromium 启动失败 (第 %d 次), %s 后重试: %v", attempt, backoff, err)
+		select {
+		case <-m.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, m.maxBackoff)
+	}
+}
+
+// start 启动浏览器并注册断开回调
+func (m *BrowserManager) start() (playwright.Browser, error) {
+	browser, err := m.launch()
+	if err != nil {
+		return nil, fmt.Errorf("could not launch browser: %w", err)
+	}
+	browser.OnDisconnected(m.onDisconnected)
+	return browser, nil
+}
+
+func (m *BrowserManager) onDisconnected(b playwright.Browser) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 主动关闭或已被替换的旧浏览器不处理
+	if m.closed || b != m.browser {
+		return
+	}
+	log.Println("Chromium 已断开，准备重新启动")
+	m.restartLocked()
+}
+
+// Close 停止看护并关闭浏览器
+func (m *BrowserManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	browser := m.browser
+	m.browser = nil
+	m.mu.Unlock()
+
+	if browser == nil {
+		return nil
+	}
+	return browser.Close()
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"github.com/playwright-community/playwright-go"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyLauncher 前 failures 次启动失败，之后每次返回新的 fakeBrowser
+type flakyLauncher struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	browsers []*fakeBrowser
+}
+
+func (l *flakyLauncher) launch() (playwright.Browser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls++
+	if l.calls <= l.failures {
+		return nil, errors.New("chromium crashed")
+	}
+	b := &fakeBrowser{}
+	l.browsers = append(l.browsers, b)
+	return b, nil
+}
+
+func (l *flakyLauncher) state() (int, []*fakeBrowser) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls, append([]*fakeBrowser(nil), l.browsers...)
+}
+
+func TestBrowserManagerRetriesLaunch(t *testing.T) {
+	l := &flakyLauncher{failures: 3}
+	m := NewBrowserManager(l.launch, time.Millisecond, 4*time.Millisecond)
+	t.Cleanup(func() { _ = m.Close() })
+
+	select {
+	case <-m.Start():
+	case <-time.After(2 * time.Second):
+		t.Fatal("浏览器未在重试后启动")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	b, err := m.Browser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls, browsers := l.state()
+	if calls != 4 || len(browsers) != 1 || b != browsers[0] {
+		t.Fatalf("启动次数 = %d, 浏览器数 = %d, 期望失败 3 次后第 4 次成功", calls, len(browsers))
+	}
+}
+
+func TestBrowserManagerRelaunchOnDisconnect(t *testing.T) {
+	l := &flakyLauncher{}
+	m := NewBrowserManager(l.launch, time.Millisecond, time.Millisecond)
+	t.Cleanup(func() { _ = m.Close() })
+	<-m.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	first, err := m.Browser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟崩溃：断开回调触发重启，且下一次启动先失败两次
+	l.mu.Lock()
+	l.failures = l.calls + 2
+	l.mu.Unlock()
+	fb := first.(*fakeBrowser)
+	fb.closed.Store(true)
+	fb.onClose(fb)
+
+	second, err := m.Browser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatal("断开后应返回新启动的浏览器")
+	}
+	if calls, _ := l.state(); calls != 4 {
+		t.Errorf("启动次数 = %d, 期望 4", calls)
+	}
+
+	// 旧浏览器的迟到回调不应再次触发重启
+	fb.onClose(fb)
+	if again, err := m.Browser(ctx); err != nil || again != second {
+		t.Errorf("旧浏览器断开不应影响当前浏览器: %v", err)
+	}
+}
+
+func TestBrowserManagerClose(t *testing.T) {
+	l := &flakyLauncher{failures: 1 << 30}
+	m := NewBrowserManager(l.launch, time.Millisecond, time.Millisecond)
+	ready := m.Start()
+	time.Sleep(10 * time.Millisecond)
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("关闭后重启循环应退出")
+	}
+	if _, err := m.Browser(context.Background()); !errors.Is(err, ErrBrowserClosed) {
+		t.Errorf("期望 ErrBrowserClosed, 实际 %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // TelegramBot 结构体
@@ -27,7 +30,7 @@ func NewTelegramBot(token, chatID string) *TelegramBot {
 	}
 }
 
-func (bot *TelegramBot) SendMessage(message string) error {
+func (bot *TelegramBot) SendMessage(ctx context.Context, message string) error {
 	apiURL := fmt.Sprintf("%s/sendMessage", bot.BaseURL)
 
 	data := url.Values{}
@@ -35,7 +38,13 @@ func (bot *TelegramBot) SendMessage(message string) error {
 	data.Set("text", message)
 	data.Set("parse_mode", "HTML")
 
-	resp, err := http.PostForm(apiURL, data)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("发送消息失败: %v", err)
 	}
@@ -50,7 +59,7 @@ func (bot *TelegramBot) SendMessage(message string) error {
 	return nil
 }
 
-func (bot *TelegramBot) SendPhoto(filePath, caption string) error {
+func (bot *TelegramBot) SendPhoto(ctx context.Context, filePath, caption string) error {
 	apiURL := fmt.Sprintf("%s/sendPhoto", bot.BaseURL)
 
 	// 打开文件
@@ -87,7 +96,7 @@ func (bot *TelegramBot) SendPhoto(filePath, caption string) error {
 	writer.Close()
 
 	// 发送请求
-	req, err := http.NewRequest("POST", apiURL, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, body)
 	if err != nil {
 		return err
 	}
@@ -108,7 +117,7 @@ func (bot *TelegramBot) SendPhoto(filePath, caption string) error {
 	return nil
 }
 
-func (bot *TelegramBot) SendDocument(filePath, caption string) error {
+func (bot *TelegramBot) SendDocument(ctx context.Context, filePath, caption string) error {
 	apiURL := fmt.Sprintf("%s/sendDocument", bot.BaseURL)
 
 	file, err := os.Open(filePath)
@@ -139,7 +148,7 @@ func (bot *TelegramBot) SendDocument(filePath, caption string) error {
 
 	writer.Close()
 
-	req, err := http.NewRequest("POST", apiURL, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, body)
 	if err != nil {
 		return err
 	}
@@ -159,3 +168,127 @@ func (bot *TelegramBot) SendDocument(filePath, caption string) error {
 	log.Printf("文件已成功发送到Telegram: %s", filePath)
 	return nil
 }
+
+func (bot *TelegramBot) SendAnimation(ctx context.Context, filePath, caption string) error {
+	apiURL := fmt.Sprintf("%s/sendAnimation", bot.BaseURL)
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("无法打开动图: %v", err)
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if err := writer.WriteField("chat_id", bot.ChatID); err != nil {
+		return err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return err
+		}
+	}
+
+	part, err := writer.CreateFormFile("animation", filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送动图失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Telegram API返回错误: %d, 响应: %s", resp.StatusCode, respBody)
+	}
+
+	log.Printf("动图已成功发送到Telegram: %s", filePath)
+	return nil
+}
+
+// SendMediaGroup 把多张图片作为一组发送，caption 显示在第一张上；Telegram 限制每组 2~10 张
+func (bot *TelegramBot) SendMediaGroup(ctx context.Context, filePaths []string, caption string) error {
+	if len(filePaths) < 2 || len(filePaths) > 10 {
+		return fmt.Errorf("媒体组需要 2~10 个文件, 实际: %d", len(filePaths))
+	}
+	apiURL := fmt.Sprintf("%s/sendMediaGroup", bot.BaseURL)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if err := writer.WriteField("chat_id", bot.ChatID); err != nil {
+		return err
+	}
+
+	type inputMedia struct {
+		Type    string `json:"type"`
+		Media   string `json:"media"`
+		Caption string `json:"caption,omitempty"`
+	}
+	media := make([]inputMedia, 0, len(filePaths))
+	for i, filePath := range filePaths {
+		name := fmt.Sprintf("file%d", i)
+		item := inputMedia{Type: "photo", Media: "attach://" + name}
+		if i == 0 {
+			item.Caption = caption
+		}
+		media = append(media, item)
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("无法打开图片: %v", err)
+		}
+		part, err := writer.CreateFormFile(name, filepath.Base(filePath))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	mediaJSON, err := json.Marshal(media)
+	if err != nil {
+		return err
+	}
+	if err := writer.WriteField("media", string(mediaJSON)); err != nil {
+		return err
+	}
+
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送媒体组失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Telegram API返回错误: %d, 响应: %s", resp.StatusCode, respBody)
+	}
+
+	log.Printf("媒体组已成功发送到Telegram: %d 张", len(filePaths))
+	return nil
+}
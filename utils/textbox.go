@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"html"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// 单个区域前后文本的最大展示长度（字符）
+	regionTextLimit = 300
+	// Telegram 单条消息最多 4096 字符，预留一些余量
+	telegramTextLimit = 4000
+)
+
+// TextBox 页面上一个可见文本节点的内容和在整页截图中的位置
+type TextBox struct {
+	Text   string  `json:"text"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (b TextBox) rect() image.Rectangle {
+	return image.Rect(int(b.X), int(b.Y), int(b.X+b.Width+0.5), int(b.Y+b.Height+0.5))
+}
+
+// collectTextBoxesJS 遍历 body 下的可见文本节点，返回文档坐标（与整页截图坐标一致）
+const collectTextBoxesJS = `() => {
+	const out = [];
+	const walker = document.createTreeWalker(document.body, NodeFilter.SHOW_TEXT);
+	const range = document.createRange();
+	while (walker.nextNode()) {
+		const node = walker.currentNode;
+		const el = node.parentElement;
+		if (!el || ['SCRIPT', 'STYLE', 'NOSCRIPT'].includes(el.tagName)) continue;
+		const text = node.textContent.replace(/\s+/g, ' ').trim();
+		if (!text) continue;
+		const style = getComputedStyle(el);
+		if (style.visibility === 'hidden' || style.display === 'none' || style.opacity === '0') continue;
+		range.selectNodeContents(node);
+		const r = range.getBoundingClientRect();
+		if (r.width === 0 || r.height === 0) continue;
+		out.push({text, x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height});
+	}
+	return out;
+}`
+
+// collectTextBoxes 采集页面上所有可见文本节点及其位置
+func collectTextBoxes(page playwright.Page) ([]TextBox, error) {
+	res, err := page.Evaluate(collectTextBoxesJS)
+	if err != nil {
+		return nil, fmt.Errorf("could not collect text boxes: %w", err)
+	}
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	var boxes []TextBox
+	if err := json.Unmarshal(raw, &boxes); err != nil {
+		return nil, err
+	}
+	return boxes, nil
+}
+
+func saveTextBoxes(boxes []TextBox, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(boxes)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadTextBoxes 读取基线对应的文本位置，旧版本没有该文件时返回空
+func loadTextBoxes(path string) ([]TextBox, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var boxes []TextBox
+	if err := json.Unmarshal(data, &boxes); err != nil {
+		return nil, err
+	}
+	return boxes, nil
+}
+
+// textInRegion 按阅读顺序（从上到下、从左到右）拼接与区域相交的文本
+func textInRegion(boxes []TextBox, r image.Rectangle) string {
+	var hit []TextBox
+	for _, b := range boxes {
+		if b.rect().Overlaps(r) {
+			hit = append(hit, b)
+		}
+	}
+	sort.SliceStable(hit, func(i, j int) bool {
+		if hit[i].Y != hit[j].Y {
+			return hit[i].Y < hit[j].Y
+		}
+		return hit[i].X < hit[j].X
+	})
+	parts := make([]string, 0, len(hit))
+	for _, b := range hit {
+		parts = append(parts, b.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// RegionText 一个变化区域变化前后的文本
+type RegionText struct {
+	Rect   image.Rectangle
+	Before string
+	After  string
+}
+
+func regionTexts(before, after []TextBox, rects []image.Rectangle) []RegionText {
+	out := make([]RegionText, 0, len(rects))
+	for _, r := range rects {
+		out = append(out, RegionText{
+			Rect:   r,
+			Before: textInRegion(before, r),
+			After:  textInRegion(after, r),
+		})
+	}
+	return out
+}
+
+// anyTextChanged 是否至少有一个区域的文本发生了变化
+func anyTextChanged(regions []RegionText) bool {
+	for _, r := range regions {
+		if r.Before != r.After {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRegionTexts 生成 HTML 格式的 Telegram 消息，列出每个区域变化前后的文本
+func formatRegionTexts(regions []RegionText) string {
+	var sb strings.Builder
+	sb.WriteString("<b>变化区域文本</b>\n")
+	for i, r := range regions {
+		var entry string
+		if r.Before == r.After {
+			entry = fmt.Sprintf("\n<b>区域 %d</b> (x=%d, y=%d, %dx%d): 文本未变化\n",
+				i+1, r.Rect.Min.X, r.Rect.Min.Y, r.Rect.Dx(), r.Rect.Dy())
+		} else {
+			entry = fmt.Sprintf("\n<b>区域 %d</b> (x=%d, y=%d, %dx%d)\n旧: %s\n新: %s\n",
+				i+1, r.Rect.Min.X, r.Rect.Min.Y, r.Rect.Dx(), r.Rect.Dy(),
+				html.EscapeString(truncateRunes(r.Before, regionTextLimit)),
+				html.EscapeString(truncateRunes(r.After, regionTextLimit)))
+		}
+		if sb.Len()+len(entry) > telegramTextLimit {
+			sb.WriteString(fmt.Sprintf("\n… 其余 %d 个区域省略", len(regions)-i))
+			break
+		}
+		sb.WriteString(entry)
+	}
+	return sb.String()
+}
+
+func truncateRunes(s string, n int) string {
+	if s == "" {
+		return "（无）"
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
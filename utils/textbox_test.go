@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTextInRegion(t *testing.T) {
+	boxes := []TextBox{
+		{Text: "Add to Cart", X: 200, Y: 100, Width: 80, Height: 20},
+		{Text: "$30", X: 100, Y: 100, Width: 40, Height: 20},
+		{Text: "Patriot Hat", X: 100, Y: 60, Width: 100, Height: 20},
+		{Text: "Footer", X: 100, Y: 900, Width: 100, Height: 20},
+	}
+	cases := []struct {
+		name string
+		rect image.Rectangle
+		want string
+	}{
+		{"按行再按列排序", image.Rect(90, 50, 300, 130), "Patriot Hat $30 Add to Cart"},
+		{"部分重叠也算", image.Rect(130, 110, 150, 115), "$30"},
+		{"相邻不算重叠", image.Rect(0, 0, 100, 60), ""},
+		{"空区域", image.Rect(500, 500, 600, 600), ""},
+	}
+	for _, c := range cases {
+		if got := textInRegion(boxes, c.rect); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRegionTexts(t *testing.T) {
+	before := []TextBox{{Text: "$30", X: 10, Y: 10, Width: 30, Height: 10}, {Text: "Logo", X: 10, Y: 200, Width: 30, Height: 10}}
+	after := []TextBox{{Text: "$35", X: 10, Y: 10, Width: 30, Height: 10}, {Text: "Logo", X: 10, Y: 200, Width: 30, Height: 10}}
+	rects := []image.Rectangle{image.Rect(0, 0, 50, 30), image.Rect(0, 190, 50, 220)}
+
+	got := regionTexts(before, after, rects)
+	want := []RegionText{
+		{Rect: rects[0], Before: "$30", After: "$35"},
+		{Rect: rects[1], Before: "Logo", After: "Logo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !anyTextChanged(got) || anyTextChanged(got[1:]) {
+		t.Error("anyTextChanged 结果错误")
+	}
+}
+
+func TestFormatRegionTexts(t *testing.T) {
+	cases := []struct {
+		name    string
+		regions []RegionText
+		want    []string
+	}{
+		{"变化前后文本并转义", []RegionText{{Rect: image.Rect(1, 2, 11, 22), Before: "a<b", After: ""}},
+			[]string{"<b>区域 1</b> (x=1, y=2, 10x20)", "旧: a&lt;b", "新: （无）"}},
+		{"文本未变化", []RegionText{{Rect: image.Rect(0, 0, 5, 5), Before: "x", After: "x"}},
+			[]string{"区域 1</b> (x=0, y=0, 5x5): 文本未变化"}},
+		{"超长文本截断", []RegionText{{Before: strings.Repeat("字", regionTextLimit+5), After: "y"}},
+			[]string{strings.Repeat("字", regionTextLimit) + "…"}},
+	}
+	for _, c := range cases {
+		got := formatRegionTexts(c.regions)
+		for _, w := range c.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: 缺少 %q:\n%s", c.name, w, got)
+			}
+		}
+	}
+
+	// 超过 Telegram 长度限制时省略剩余区域
+	var many []RegionText
+	for range 20 {
+		many = append(many, RegionText{Before: strings.Repeat("a", regionTextLimit), After: strings.Repeat("b", regionTextLimit)})
+	}
+	got := formatRegionTexts(many)
+	if len(got) > telegramTextLimit+100 || !strings.Contains(got, "个区域省略") {
+		t.Errorf("长度 %d, 未省略:\n%s", len(got), got)
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/playwright-community/playwright-go"
+	"store/common"
+	"time"
+)
+
+const defaultWaitTimeout = 30 * time.Second
+
+// domStableJS 监听 DOM 变化，连续 stable 毫秒没有变化时返回，超过 timeout 毫秒仍在变化则报错
+const domStableJS = `([stable, timeout]) => new Promise((resolve, reject) => {
+	let timer, limit;
+	const finish = (ok) => {
+		observer.disconnect();
+		clearTimeout(timer);
+		clearTimeout(limit);
+		ok ? resolve(true) : reject(new Error('DOM did not settle within ' + timeout + 'ms'));
+	};
+	const observer = new MutationObserver(() => {
+		clearTimeout(timer);
+		timer = setTimeout(() => finish(true), stable);
+	});
+	observer.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+	timer = setTimeout(() => finish(true), stable);
+	limit = setTimeout(() => finish(false), timeout);
+})`
+
+// GotoOptions 打开页面的选项：没有就绪规则时沿用等待网络空闲，有规则时只等 DOMContentLoaded；
+// 导航超时取规则中配置的最长超时，都未配置时为 30 秒
+func GotoOptions(rules []common.WaitRule) playwright.PageGotoOptions {
+	timeout := time.Duration(0)
+	for _, r := range rules {
+		timeout = max(timeout, r.Timeout)
+	}
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	opts := playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+		Timeout:   playwright.Float(float64(timeout.Milliseconds())),
+	}
+	if len(rules) == 0 {
+		opts.WaitUntil = playwright.WaitUntilStateNetworkidle
+	}
+	return opts
+}
+
+// WaitReady 依次等待每条就绪规则满足
+func WaitReady(page playwright.Page, rules []common.WaitRule) error {
+	for _, r := range rules {
+		if err := waitRule(page, r); err != nil {
+			return fmt.Errorf("等待 %s %s 失败: %w", r.Kind, r.Value, err)
+		}
+	}
+	return nil
+}
+
+func waitRule(page playwright.Page, r common.WaitRule) error {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	ms := playwright.Float(float64(timeout.Milliseconds()))
+
+	switch r.Kind {
+	case common.WaitLoad:
+		state := playwright.LoadStateLoad
+		switch r.Value {
+		case "", "load":
+		case "domcontentloaded":
+			state = playwright.LoadStateDomcontentloaded
+		case "networkidle":
+			state = playwright.LoadStateNetworkidle
+		default:
+			return fmt.Errorf("未知加载状态: %s", r.Value)
+		}
+		return page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{State: state, Timeout: ms})
+	case common.WaitSelector:
+		_, err := page.WaitForSelector(r.Value, playwright.PageWaitForSelectorOptions{Timeout: ms})
+		return err
+	case common.WaitSelectorHidden:
+		_, err := page.WaitForSelector(r.Value, playwright.PageWaitForSelectorOptions{
+			State:   playwright.WaitForSelectorStateHidden,
+			Timeout: ms,
+		})
+		return err
+	case common.WaitFunction:
+		_, err := page.WaitForFunction(r.Value, nil, playwright.PageWaitForFunctionOptions{Timeout: ms})
+		return err
+	case common.WaitText:
+		return page.GetByText(r.Value).First().WaitFor(playwright.LocatorWaitForOptions{Timeout: ms})
+	case common.WaitDOMStable:
+		stable := r.Stable
+		if stable <= 0 {
+			stable = time.Second
+		}
+		_, err := page.Evaluate(domStableJS, []int64{stable.Milliseconds(), timeout.Milliseconds()})
+		return err
+	default:
+		return fmt.Errorf("未知就绪条件: %s", r.Kind)
+	}
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"github.com/playwright-community/playwright-go"
+	"store/common"
+	"testing"
+	"time"
+)
+
+func TestGotoOptions(t *testing.T) {
+	opts := GotoOptions(nil)
+	if *opts.WaitUntil != *playwright.WaitUntilStateNetworkidle || *opts.Timeout != 30000 {
+		t.Errorf("无规则时 = %v %v, 期望 networkidle 30000", *opts.WaitUntil, *opts.Timeout)
+	}
+
+	opts = GotoOptions([]common.WaitRule{
+		{Kind: common.WaitLoad, Value: "networkidle", Timeout: 60 * time.Second},
+		{Kind: common.WaitSelector, Value: "#app", Timeout: 10 * time.Second},
+	})
+	if *opts.WaitUntil != *playwright.WaitUntilStateDomcontentloaded || *opts.Timeout != 60000 {
+		t.Errorf("有规则时 = %v %v, 期望 domcontentloaded 60000", *opts.WaitUntil, *opts.Timeout)
+	}
+
+	// 规则未配置超时时使用默认值
+	if opts = GotoOptions([]common.WaitRule{{Kind: common.WaitSelector, Value: "#app"}}); *opts.Timeout != 30000 {
+		t.Errorf("默认导航超时 = %v, 期望 30000", *opts.Timeout)
+	}
+}